@@ -0,0 +1,81 @@
+package main
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+type benchPayload struct {
+	name string
+	test Test
+}
+
+var (
+	payloadsOnce sync.Once
+	payloads     []benchPayload
+)
+
+// benchPayloads generates the simple and full payloads once, so every
+// codec is measured on the same data.
+func benchPayloads(b testing.TB) []benchPayload {
+	payloadsOnce.Do(func() {
+		for _, c := range codecs {
+			if s, ok := c.(Setuper); ok {
+				if err := s.Setup(); err != nil {
+					b.Fatalf("%s setup: %v", c.Name(), err)
+				}
+			}
+		}
+		rnd := rand.New(rand.NewSource(1))
+		payloads = []benchPayload{
+			{"simple", generateTest(rnd, simpleShape)},
+			{"full", generateTest(rnd, fullShape)},
+		}
+	})
+	return payloads
+}
+
+func BenchmarkMarshal(b *testing.B) {
+	for _, c := range withReuse(codecs) {
+		for _, p := range benchPayloads(b) {
+			c, v := c, modelOf(c, p.test)
+			b.Run(c.Name()+"/"+p.name, func(b *testing.B) {
+				data, err := c.Marshal(v)
+				if err != nil {
+					b.Fatal(err)
+				}
+				b.SetBytes(int64(len(data)))
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if _, err := c.Marshal(v); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkUnmarshal(b *testing.B) {
+	for _, c := range withReuse(codecs) {
+		for _, p := range benchPayloads(b) {
+			c := c
+			data, err := c.Marshal(modelOf(c, p.test))
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.Run(c.Name()+"/"+p.name, func(b *testing.B) {
+				b.SetBytes(int64(len(data)))
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if err := c.Unmarshal(data, newModel(c)); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
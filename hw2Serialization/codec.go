@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Codec is one serialization format taking part in the benchmark.
+// Name is used in the -formats flag, in reports and as the file name under files/.
+type Codec interface {
+	Name() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// Setuper is implemented by codecs that need one-time preparation
+// (e.g. parsing a schema) before the first Marshal.
+type Setuper interface {
+	Setup() error
+}
+
+// Modeler is implemented by codecs that do not work on Test directly
+// but on their own generated type (e.g. protobuf). Test converts a
+// decoded model back for verification.
+type Modeler interface {
+	Model(t Test) interface{}
+	NewModel() interface{}
+	Test(v interface{}) Test
+}
+
+// newModeler is the part of Modeler newModel needs. The -schema-proto
+// and -schema-avro codecs work on values that are no Test at all and
+// implement only it.
+type newModeler interface {
+	NewModel() interface{}
+}
+
+var (
+	codecs []Codec
+	// optIn holds the names of the codecs "all" leaves out.
+	optIn = map[string]bool{}
+)
+
+func register(c Codec) {
+	codecs = append(codecs, c)
+}
+
+// registerOptIn registers a codec known to lose data, e.g. by rounding
+// floats. It runs only when named in -formats, so a default run still
+// fails only on real data loss.
+func registerOptIn(c Codec) {
+	register(c)
+	optIn[c.Name()] = true
+}
+
+// modelOf returns the value c should marshal for t.
+func modelOf(c Codec, t Test) interface{} {
+	if m, ok := c.(Modeler); ok {
+		return m.Model(t)
+	}
+	return t
+}
+
+// newModel returns a pointer c can unmarshal into.
+func newModel(c Codec) interface{} {
+	if m, ok := c.(newModeler); ok {
+		return m.NewModel()
+	}
+	return &Test{}
+}
+
+// selectCodecs resolves a comma separated list of codec names (case insensitive).
+// An empty list or "all" selects every registered codec but the opt-in ones.
+func selectCodecs(list string) ([]Codec, error) {
+	list = strings.TrimSpace(list)
+	if list == "" || strings.EqualFold(list, "all") {
+		var all []Codec
+		for _, c := range codecs {
+			if !optIn[c.Name()] {
+				all = append(all, c)
+			}
+		}
+		return all, nil
+	}
+
+	var selected []Codec
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		c := lookupCodec(name)
+		if c == nil {
+			return nil, fmt.Errorf("unknown format %q (known: %s)", name, strings.Join(codecNames(), ","))
+		}
+		selected = append(selected, c)
+	}
+	return selected, nil
+}
+
+func lookupCodec(name string) Codec {
+	for _, c := range codecs {
+		if strings.EqualFold(c.Name(), name) {
+			return c
+		}
+	}
+	return nil
+}
+
+func codecNames() []string {
+	names := make([]string, len(codecs))
+	for i, c := range codecs {
+		names[i] = c.Name()
+	}
+	return names
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+
+	models "hw2Serialization/models"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/hamba/avro/v2"
+	"github.com/vmihailenco/msgpack"
+	"go.mongodb.org/mongo-driver/bson"
+	"google.golang.org/protobuf/proto"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const avroSchemaPath = "./models/schema.avsc"
+
+func init() {
+	register(gobCodec{})
+	register(xmlCodec{})
+	register(jsonCodec{})
+	register(&protoCodec{})
+	register(&avroCodec{})
+	register(yamlCodec{})
+	register(msgpCodec{})
+	register(cborCodec{})
+	register(bsonCodec{})
+}
+
+type gobCodec struct{}
+
+func (gobCodec) Name() string { return "Gob" }
+
+func (gobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buff bytes.Buffer
+	err := gob.NewEncoder(&buff).Encode(v)
+	return buff.Bytes(), err
+}
+
+func (gobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+type xmlCodec struct{}
+
+func (xmlCodec) Name() string { return "XML" }
+
+func (xmlCodec) Marshal(v interface{}) ([]byte, error) {
+	return xml.Marshal(v)
+}
+
+func (xmlCodec) Unmarshal(data []byte, v interface{}) error {
+	return xml.Unmarshal(data, v)
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string { return "Json" }
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// protoCodec works on the generated models.Test.
+type protoCodec struct{}
+
+func (*protoCodec) Name() string { return "Proto" }
+
+func (*protoCodec) Model(t Test) interface{} {
+	return &models.Test{
+		ID:               t.ID,
+		Name:             t.Name,
+		SomeNumericArray: t.SomeNumericArray,
+		SomeFloatArray:   t.SomeFloatArray,
+		Tests:            protoTestStructs(t.Tests),
+		Comment:          t.Comment,
+		Labels:           t.Labels,
+		Blob:             t.Blob,
+	}
+}
+
+func protoTestStructs(in []TestStruct) []*models.TestStruct {
+	if in == nil {
+		return nil
+	}
+	out := make([]*models.TestStruct, len(in))
+	for i, ts := range in {
+		out[i] = &models.TestStruct{Some: ts.Some, Other: ts.Other, Children: protoTestStructs(ts.Children)}
+	}
+	return out
+}
+
+func (*protoCodec) NewModel() interface{} { return &models.Test{} }
+
+func (*protoCodec) Test(v interface{}) Test {
+	m := v.(*models.Test)
+	return Test{
+		ID:               m.GetID(),
+		Name:             m.GetName(),
+		SomeNumericArray: m.GetSomeNumericArray(),
+		SomeFloatArray:   m.GetSomeFloatArray(),
+		Tests:            testStructs(m.GetTests()),
+		Comment:          m.Comment,
+		Labels:           m.GetLabels(),
+		Blob:             m.GetBlob(),
+	}
+}
+
+func testStructs(in []*models.TestStruct) []TestStruct {
+	if in == nil {
+		return nil
+	}
+	out := make([]TestStruct, len(in))
+	for i, ts := range in {
+		out[i] = TestStruct{Some: ts.GetSome(), Other: ts.GetOther(), Children: testStructs(ts.GetChildren())}
+	}
+	return out
+}
+
+func (*protoCodec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("proto: %T is not a proto.Message", v)
+	}
+	return proto.Marshal(m)
+}
+
+func (*protoCodec) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("proto: %T is not a proto.Message", v)
+	}
+	return proto.Unmarshal(data, m)
+}
+
+// avroCodec needs models/schema.avsc parsed by Setup before use.
+type avroCodec struct {
+	schema avro.Schema
+}
+
+func (*avroCodec) Name() string { return "Avro" }
+
+func (c *avroCodec) Setup() error {
+	schemaStr, err := ioutil.ReadFile(avroSchemaPath)
+	if err != nil {
+		return fmt.Errorf("schema reading error: %v", err)
+	}
+	c.schema, err = avro.Parse(string(schemaStr))
+	if err != nil {
+		return fmt.Errorf("schema parsing error: %v", err)
+	}
+	return nil
+}
+
+func (c *avroCodec) Marshal(v interface{}) ([]byte, error) {
+	return avro.Marshal(c.schema, v)
+}
+
+func (c *avroCodec) Unmarshal(data []byte, v interface{}) error {
+	return avro.Unmarshal(c.schema, data, v)
+}
+
+type yamlCodec struct{}
+
+func (yamlCodec) Name() string { return "YAML" }
+
+func (yamlCodec) Marshal(v interface{}) ([]byte, error) {
+	return yaml.Marshal(v)
+}
+
+func (yamlCodec) Unmarshal(data []byte, v interface{}) error {
+	return yaml.Unmarshal(data, v)
+}
+
+type msgpCodec struct{}
+
+func (msgpCodec) Name() string { return "MSG" }
+
+func (msgpCodec) Marshal(v interface{}) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (msgpCodec) Unmarshal(data []byte, v interface{}) error {
+	return msgpack.Unmarshal(data, v)
+}
+
+type cborCodec struct{}
+
+func (cborCodec) Name() string { return "CBOR" }
+
+func (cborCodec) Marshal(v interface{}) ([]byte, error) {
+	return cbor.Marshal(v)
+}
+
+func (cborCodec) Unmarshal(data []byte, v interface{}) error {
+	return cbor.Unmarshal(data, v)
+}
+
+type bsonCodec struct{}
+
+func (bsonCodec) Name() string { return "BSON" }
+
+func (bsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return bson.Marshal(v)
+}
+
+func (bsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return bson.Unmarshal(data, v)
+}
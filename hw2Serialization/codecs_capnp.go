@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	capn "hw2Serialization/models/capn"
+
+	capnp "capnproto.org/go/capnp/v3"
+)
+
+func init() {
+	register(capnpCodec{})
+}
+
+// capnpCodec encodes Test as a test.capnp message; models/capn is the
+// capnpc-go output for it.
+type capnpCodec struct{}
+
+func (capnpCodec) Name() string { return "CapnProto" }
+
+func (capnpCodec) Marshal(v interface{}) ([]byte, error) {
+	t, err := asTest(v)
+	if err != nil {
+		return nil, err
+	}
+	msg, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		return nil, err
+	}
+	root, err := capn.NewRootTest(seg)
+	if err != nil {
+		return nil, err
+	}
+	if err := buildCapnTest(root, t); err != nil {
+		return nil, err
+	}
+	return msg.Marshal()
+}
+
+func (capnpCodec) Unmarshal(data []byte, v interface{}) error {
+	out, ok := v.(*Test)
+	if !ok {
+		return fmt.Errorf("capnp: cannot decode into %T", v)
+	}
+	msg, err := capnp.Unmarshal(data)
+	if err != nil {
+		return err
+	}
+	root, err := capn.ReadRootTest(msg)
+	if err != nil {
+		return err
+	}
+	return readCapnTest(root, out)
+}
+
+func buildCapnTest(c capn.Test, t *Test) error {
+	c.SetId(t.ID)
+	if err := c.SetName(t.Name); err != nil {
+		return err
+	}
+
+	// Lists are only set when non-nil: a null pointer reads back as nil and
+	// an empty list as empty, so both survive the round trip.
+	if t.SomeNumericArray != nil {
+		numbers, err := c.NewSomeNumericArray(int32(len(t.SomeNumericArray)))
+		if err != nil {
+			return err
+		}
+		for i, n := range t.SomeNumericArray {
+			numbers.Set(i, n)
+		}
+	}
+
+	if t.SomeFloatArray != nil {
+		floats, err := c.NewSomeFloatArray(int32(len(t.SomeFloatArray)))
+		if err != nil {
+			return err
+		}
+		for i, f := range t.SomeFloatArray {
+			floats.Set(i, f)
+		}
+	}
+
+	if t.Tests != nil {
+		tests, err := c.NewTests(int32(len(t.Tests)))
+		if err != nil {
+			return err
+		}
+		if err := buildCapnTestStructs(tests, t.Tests); err != nil {
+			return err
+		}
+	}
+
+	if t.Comment != nil {
+		c.SetCommentSet(true)
+		if err := c.SetComment(*t.Comment); err != nil {
+			return err
+		}
+	}
+
+	if t.Labels != nil {
+		keys := make([]string, 0, len(t.Labels))
+		for k := range t.Labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		labels, err := c.NewLabels(int32(len(keys)))
+		if err != nil {
+			return err
+		}
+		for i, k := range keys {
+			l := labels.At(i)
+			if err := l.SetKey(k); err != nil {
+				return err
+			}
+			if err := l.SetValue(t.Labels[k]); err != nil {
+				return err
+			}
+		}
+	}
+
+	// SetBlob stores a nil slice as a null pointer and an empty one as empty Data.
+	return c.SetBlob(t.Blob)
+}
+
+func buildCapnTestStructs(list capn.TestStruct_List, ts []TestStruct) error {
+	for i := range ts {
+		s := list.At(i)
+		if err := s.SetSome(ts[i].Some); err != nil {
+			return err
+		}
+		if err := s.SetOther(ts[i].Other); err != nil {
+			return err
+		}
+		if ts[i].Children == nil {
+			continue
+		}
+		children, err := s.NewChildren(int32(len(ts[i].Children)))
+		if err != nil {
+			return err
+		}
+		if err := buildCapnTestStructs(children, ts[i].Children); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readCapnTest(c capn.Test, out *Test) error {
+	name, err := c.Name()
+	if err != nil {
+		return err
+	}
+	t := Test{ID: c.Id(), Name: name}
+
+	if c.HasSomeNumericArray() {
+		numbers, err := c.SomeNumericArray()
+		if err != nil {
+			return err
+		}
+		t.SomeNumericArray = make([]int32, numbers.Len())
+		for i := range t.SomeNumericArray {
+			t.SomeNumericArray[i] = numbers.At(i)
+		}
+	}
+
+	if c.HasSomeFloatArray() {
+		floats, err := c.SomeFloatArray()
+		if err != nil {
+			return err
+		}
+		t.SomeFloatArray = make([]float32, floats.Len())
+		for i := range t.SomeFloatArray {
+			t.SomeFloatArray[i] = floats.At(i)
+		}
+	}
+
+	if c.HasTests() {
+		tests, err := c.Tests()
+		if err != nil {
+			return err
+		}
+		if t.Tests, err = readCapnTestStructs(tests); err != nil {
+			return err
+		}
+	}
+
+	if c.CommentSet() {
+		comment, err := c.Comment()
+		if err != nil {
+			return err
+		}
+		t.Comment = &comment
+	}
+
+	if c.HasLabels() {
+		labels, err := c.Labels()
+		if err != nil {
+			return err
+		}
+		t.Labels = make(Labels, labels.Len())
+		for i := 0; i < labels.Len(); i++ {
+			l := labels.At(i)
+			k, err := l.Key()
+			if err != nil {
+				return err
+			}
+			if t.Labels[k], err = l.Value(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if c.HasBlob() {
+		blob, err := c.Blob()
+		if err != nil {
+			return err
+		}
+		// Blob points into data, which the caller may reuse.
+		t.Blob = append([]byte{}, blob...)
+	}
+
+	*out = t
+	return nil
+}
+
+func readCapnTestStructs(list capn.TestStruct_List) ([]TestStruct, error) {
+	ts := make([]TestStruct, list.Len())
+	for i := range ts {
+		s := list.At(i)
+		var err error
+		if ts[i].Some, err = s.Some(); err != nil {
+			return nil, err
+		}
+		if ts[i].Other, err = s.Other(); err != nil {
+			return nil, err
+		}
+		if !s.HasChildren() {
+			continue
+		}
+		children, err := s.Children()
+		if err != nil {
+			return nil, err
+		}
+		if ts[i].Children, err = readCapnTestStructs(children); err != nil {
+			return nil, err
+		}
+	}
+	return ts, nil
+}
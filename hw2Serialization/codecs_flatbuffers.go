@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	fb "hw2Serialization/models/fb"
+
+	flatbuffers "github.com/google/flatbuffers/go"
+)
+
+func init() {
+	register(flatbuffersCodec{})
+}
+
+// flatbuffersCodec builds a test.fbs buffer from Test and reads it back
+// field by field, so decoding costs as much as for the other codecs.
+type flatbuffersCodec struct{}
+
+func (flatbuffersCodec) Name() string { return "FlatBuffers" }
+
+func (flatbuffersCodec) Marshal(v interface{}) ([]byte, error) {
+	t, err := asTest(v)
+	if err != nil {
+		return nil, err
+	}
+	b := flatbuffers.NewBuilder(1024)
+	fb.FinishTestBuffer(b, buildFBTest(b, t))
+	return b.FinishedBytes(), nil
+}
+
+func (flatbuffersCodec) Unmarshal(data []byte, v interface{}) (err error) {
+	out, ok := v.(*Test)
+	if !ok {
+		return fmt.Errorf("flatbuffers: cannot decode into %T", v)
+	}
+	// The generated accessors index into data without bounds checks.
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("flatbuffers: malformed buffer: %v", r)
+		}
+	}()
+	*out = readFBTest(fb.GetRootAsTest(data, 0))
+	return nil
+}
+
+// asTest accepts Test by value or by pointer, as codecs working on Test are given both.
+func asTest(v interface{}) (*Test, error) {
+	switch t := v.(type) {
+	case Test:
+		return &t, nil
+	case *Test:
+		return t, nil
+	}
+	return nil, fmt.Errorf("cannot encode %T", v)
+}
+
+func buildFBTest(b *flatbuffers.Builder, t *Test) flatbuffers.UOffsetT {
+	name := b.CreateString(t.Name)
+
+	fb.TestStartSomeNumericArrayVector(b, len(t.SomeNumericArray))
+	for i := len(t.SomeNumericArray) - 1; i >= 0; i-- {
+		b.PrependInt32(t.SomeNumericArray[i])
+	}
+	numbers := b.EndVector(len(t.SomeNumericArray))
+
+	fb.TestStartSomeFloatArrayVector(b, len(t.SomeFloatArray))
+	for i := len(t.SomeFloatArray) - 1; i >= 0; i-- {
+		b.PrependFloat32(t.SomeFloatArray[i])
+	}
+	floats := b.EndVector(len(t.SomeFloatArray))
+
+	tests := buildFBTestStructs(b, t.Tests)
+
+	var comment flatbuffers.UOffsetT
+	if t.Comment != nil {
+		comment = b.CreateString(*t.Comment)
+	}
+
+	var labels flatbuffers.UOffsetT
+	if len(t.Labels) > 0 {
+		keys := make([]string, 0, len(t.Labels))
+		for k := range t.Labels {
+			keys = append(keys, k)
+		}
+		// Label.key is a (key) field: the vector must be sorted for LabelsByKey.
+		sort.Strings(keys)
+		offsets := make([]flatbuffers.UOffsetT, len(keys))
+		for i, k := range keys {
+			key, value := b.CreateString(k), b.CreateString(t.Labels[k])
+			fb.LabelStart(b)
+			fb.LabelAddKey(b, key)
+			fb.LabelAddValue(b, value)
+			offsets[i] = fb.LabelEnd(b)
+		}
+		fb.TestStartLabelsVector(b, len(offsets))
+		for i := len(offsets) - 1; i >= 0; i-- {
+			b.PrependUOffsetT(offsets[i])
+		}
+		labels = b.EndVector(len(offsets))
+	}
+
+	var blob flatbuffers.UOffsetT
+	if t.Blob != nil {
+		blob = b.CreateByteVector(t.Blob)
+	}
+
+	fb.TestStart(b)
+	fb.TestAddId(b, t.ID)
+	fb.TestAddName(b, name)
+	fb.TestAddSomeNumericArray(b, numbers)
+	fb.TestAddSomeFloatArray(b, floats)
+	fb.TestAddTests(b, tests)
+	if t.Comment != nil {
+		fb.TestAddComment(b, comment)
+	}
+	if labels != 0 {
+		fb.TestAddLabels(b, labels)
+	}
+	if blob != 0 {
+		fb.TestAddBlob(b, blob)
+	}
+	return fb.TestEnd(b)
+}
+
+func buildFBTestStructs(b *flatbuffers.Builder, in []TestStruct) flatbuffers.UOffsetT {
+	offsets := make([]flatbuffers.UOffsetT, len(in))
+	for i, ts := range in {
+		some, other := b.CreateString(ts.Some), b.CreateString(ts.Other)
+		var children flatbuffers.UOffsetT
+		if len(ts.Children) > 0 {
+			children = buildFBTestStructs(b, ts.Children)
+		}
+		fb.TestStructStart(b)
+		fb.TestStructAddSome(b, some)
+		fb.TestStructAddOther(b, other)
+		if children != 0 {
+			fb.TestStructAddChildren(b, children)
+		}
+		offsets[i] = fb.TestStructEnd(b)
+	}
+
+	b.StartVector(4, len(offsets), 4)
+	for i := len(offsets) - 1; i >= 0; i-- {
+		b.PrependUOffsetT(offsets[i])
+	}
+	return b.EndVector(len(offsets))
+}
+
+func readFBTest(m *fb.Test) Test {
+	t := Test{
+		ID:   m.Id(),
+		Name: string(m.Name()),
+	}
+
+	if n := m.SomeNumericArrayLength(); n > 0 {
+		t.SomeNumericArray = make([]int32, n)
+		for i := range t.SomeNumericArray {
+			t.SomeNumericArray[i] = m.SomeNumericArray(i)
+		}
+	}
+	if n := m.SomeFloatArrayLength(); n > 0 {
+		t.SomeFloatArray = make([]float32, n)
+		for i := range t.SomeFloatArray {
+			t.SomeFloatArray[i] = m.SomeFloatArray(i)
+		}
+	}
+
+	var ts fb.TestStruct
+	if n := m.TestsLength(); n > 0 {
+		t.Tests = make([]TestStruct, n)
+		for i := range t.Tests {
+			m.Tests(&ts, i)
+			t.Tests[i] = readFBTestStruct(&ts)
+		}
+	}
+
+	if c := m.Comment(); c != nil {
+		comment := string(c)
+		t.Comment = &comment
+	}
+
+	var label fb.Label
+	if n := m.LabelsLength(); n > 0 {
+		t.Labels = make(Labels, n)
+		for i := 0; i < n; i++ {
+			m.Labels(&label, i)
+			t.Labels[string(label.Key())] = string(label.Value())
+		}
+	}
+
+	if blob := m.BlobBytes(); blob != nil {
+		t.Blob = append([]byte(nil), blob...)
+	}
+	return t
+}
+
+func readFBTestStruct(m *fb.TestStruct) TestStruct {
+	ts := TestStruct{Some: string(m.Some()), Other: string(m.Other())}
+	if n := m.ChildrenLength(); n > 0 {
+		var child fb.TestStruct
+		ts.Children = make([]TestStruct, n)
+		for i := range ts.Children {
+			m.Children(&child, i)
+			ts.Children[i] = readFBTestStruct(&child)
+		}
+	}
+	return ts
+}
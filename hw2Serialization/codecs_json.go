@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/bytedance/sonic"
+	gojson "github.com/goccy/go-json"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/mailru/easyjson"
+	segjson "github.com/segmentio/encoding/json"
+)
+
+func init() {
+	register(jsonEngine{"Jsoniter", jsoniter.ConfigCompatibleWithStandardLibrary.Marshal, jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal})
+	registerOptIn(jsonEngine{"JsoniterFastest", jsoniter.ConfigFastest.Marshal, jsoniter.ConfigFastest.Unmarshal})
+	register(jsonEngine{"GoJson", gojson.Marshal, gojson.Unmarshal})
+	register(jsonEngine{"Sonic", sonic.ConfigStd.Marshal, sonic.ConfigStd.Unmarshal})
+	register(jsonEngine{"Segmentio", segjson.Marshal, segjson.Unmarshal})
+	register(easyjsonCodec{})
+}
+
+// jsonEngine is a drop-in replacement for encoding/json producing the same
+// document as the Json codec, so sizes match and only speed differs.
+// ConfigFastest trades that for speed: floats are rounded to 6 decimal
+// places, so it loses precision on small values, fails verification and
+// is opt-in.
+//
+// Sonic falls back to encoding/json on CPUs and Go versions its JIT does
+// not support, so it can be benchmarked anywhere.
+type jsonEngine struct {
+	name      string
+	marshal   func(v interface{}) ([]byte, error)
+	unmarshal func(data []byte, v interface{}) error
+}
+
+func (e jsonEngine) Name() string { return e.name }
+
+func (e jsonEngine) Marshal(v interface{}) ([]byte, error) {
+	return e.marshal(v)
+}
+
+func (e jsonEngine) Unmarshal(data []byte, v interface{}) error {
+	return e.unmarshal(data, v)
+}
+
+// easyjsonCodec uses the marshalers generated into main_easyjson.go
+// (easyjson -no_std_marshalers main.go), without reflection.
+type easyjsonCodec struct{}
+
+func (easyjsonCodec) Name() string { return "EasyJson" }
+
+func (easyjsonCodec) Marshal(v interface{}) ([]byte, error) {
+	t, err := asTest(v)
+	if err != nil {
+		return nil, err
+	}
+	return easyjson.Marshal(t)
+}
+
+func (easyjsonCodec) Unmarshal(data []byte, v interface{}) error {
+	u, ok := v.(easyjson.Unmarshaler)
+	if !ok {
+		return fmt.Errorf("easyjson: cannot decode into %T", v)
+	}
+	return easyjson.Unmarshal(data, u)
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	lz4 "github.com/bkaradzic/go-lz4"
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compressor is an optional stage applied to the output of every codec,
+// selected with -compress.
+type Compressor interface {
+	Name() string
+	Compress(data []byte) ([]byte, error)
+	Decompress(data []byte) ([]byte, error)
+}
+
+var compressors []Compressor
+
+func registerCompressor(c Compressor) {
+	compressors = append(compressors, c)
+}
+
+func init() {
+	registerCompressor(gzipCompressor{})
+	registerCompressor(newZstdCompressor())
+	registerCompressor(snappyCompressor{})
+	registerCompressor(lz4Compressor{})
+	registerCompressor(brotliCompressor{})
+}
+
+// selectCompressors resolves a comma separated list of compressor names
+// (case insensitive). Unlike -formats an empty list selects none.
+func selectCompressors(list string) ([]Compressor, error) {
+	list = strings.TrimSpace(list)
+	if list == "" {
+		return nil, nil
+	}
+	if strings.EqualFold(list, "all") {
+		return compressors, nil
+	}
+
+	var selected []Compressor
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		var found Compressor
+		for _, c := range compressors {
+			if strings.EqualFold(c.Name(), name) {
+				found = c
+			}
+		}
+		if found == nil {
+			names := make([]string, len(compressors))
+			for i, c := range compressors {
+				names[i] = c.Name()
+			}
+			return nil, fmt.Errorf("unknown compression %q (known: %s)", name, strings.Join(names, ","))
+		}
+		selected = append(selected, found)
+	}
+	return selected, nil
+}
+
+// compressRun is one measured compression round over a codec's output.
+type compressRun struct {
+	size                 int
+	compress, decompress time.Duration
+}
+
+// compressAll runs every compressor over data and checks that it
+// decompresses back to the same bytes.
+func compressAll(comps []Compressor, data []byte) ([]compressRun, error) {
+	runs := make([]compressRun, len(comps))
+	for i, c := range comps {
+		start := time.Now()
+		packed, err := c.Compress(data)
+		runs[i].compress = time.Since(start)
+		if err != nil {
+			return runs, fmt.Errorf("%s compression error: %v", c.Name(), err)
+		}
+		runs[i].size = len(packed)
+
+		start = time.Now()
+		unpacked, err := c.Decompress(packed)
+		runs[i].decompress = time.Since(start)
+		if err != nil {
+			return runs, fmt.Errorf("%s decompression error: %v", c.Name(), err)
+		}
+		if !bytes.Equal(unpacked, data) {
+			return runs, fmt.Errorf("%s: decompressed data differs from the input", c.Name())
+		}
+	}
+	return runs, nil
+}
+
+// compressTotals collects every sample of one compressor for one codec.
+type compressTotals struct {
+	name                 string
+	size                 int
+	compress, decompress []time.Duration
+}
+
+// CompressionResult is the cost of one compressor on top of a codec.
+// Ratio is the raw size divided by the compressed size.
+type CompressionResult struct {
+	Algorithm  string  `json:"algorithm"`
+	Size       int     `json:"size"`
+	Ratio      float64 `json:"ratio"`
+	Compress   Stats   `json:"compress"`
+	Decompress Stats   `json:"decompress"`
+}
+
+func (s *compressTotals) result(raw int) CompressionResult {
+	r := CompressionResult{
+		Algorithm:  s.name,
+		Size:       s.size,
+		Compress:   computeStats(s.compress),
+		Decompress: computeStats(s.decompress),
+	}
+	if s.size > 0 {
+		r.Ratio = float64(raw) / float64(s.size)
+	}
+	return r
+}
+
+type gzipCompressor struct{}
+
+func (gzipCompressor) Name() string { return "gzip" }
+
+func (gzipCompressor) Compress(data []byte) ([]byte, error) {
+	var buff bytes.Buffer
+	w := gzip.NewWriter(&buff)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	err := w.Close()
+	return buff.Bytes(), err
+}
+
+func (gzipCompressor) Decompress(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+// zstdCompressor reuses one encoder and decoder, as zstd recommends;
+// EncodeAll and DecodeAll are safe for concurrent use.
+type zstdCompressor struct {
+	enc *zstd.Encoder
+	dec *zstd.Decoder
+}
+
+func newZstdCompressor() *zstdCompressor {
+	// With a nil writer/reader and no options these cannot fail.
+	enc, _ := zstd.NewWriter(nil)
+	dec, _ := zstd.NewReader(nil)
+	return &zstdCompressor{enc: enc, dec: dec}
+}
+
+func (*zstdCompressor) Name() string { return "zstd" }
+
+func (z *zstdCompressor) Compress(data []byte) ([]byte, error) {
+	return z.enc.EncodeAll(data, nil), nil
+}
+
+func (z *zstdCompressor) Decompress(data []byte) ([]byte, error) {
+	return z.dec.DecodeAll(data, nil)
+}
+
+type snappyCompressor struct{}
+
+func (snappyCompressor) Name() string { return "snappy" }
+
+func (snappyCompressor) Compress(data []byte) ([]byte, error) {
+	return snappy.Encode(nil, data), nil
+}
+
+func (snappyCompressor) Decompress(data []byte) ([]byte, error) {
+	return snappy.Decode(nil, data)
+}
+
+// lz4Compressor uses the LZ4 block format with the uncompressed length
+// in front, which is what message queues usually carry.
+type lz4Compressor struct{}
+
+func (lz4Compressor) Name() string { return "lz4" }
+
+func (lz4Compressor) Compress(data []byte) ([]byte, error) {
+	return lz4.Encode(nil, data)
+}
+
+func (lz4Compressor) Decompress(data []byte) ([]byte, error) {
+	return lz4.Decode(nil, data)
+}
+
+type brotliCompressor struct{}
+
+func (brotliCompressor) Name() string { return "brotli" }
+
+func (brotliCompressor) Compress(data []byte) ([]byte, error) {
+	var buff bytes.Buffer
+	w := brotli.NewWriter(&buff)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	err := w.Close()
+	return buff.Bytes(), err
+}
+
+func (brotliCompressor) Decompress(data []byte) ([]byte, error) {
+	return ioutil.ReadAll(brotli.NewReader(bytes.NewReader(data)))
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// formatExtensions maps file extensions to codec names for -from and -to
+// detection; files/<Codec> artifacts are recognised by their name. Other
+// extensions, .bin included, are left to detection by content.
+var formatExtensions = map[string]string{
+	".gob":     "Gob",
+	".xml":     "XML",
+	".json":    "Json",
+	".pb":      "Proto",
+	".avro":    "Avro",
+	".yaml":    "YAML",
+	".yml":     "YAML",
+	".msgpack": "MSG",
+	".mp":      "MSG",
+	".cbor":    "CBOR",
+	".bson":    "BSON",
+	".fb":      "FlatBuffers",
+}
+
+// codecByName picks the codec from the file name: files/Avro or
+// payload.avro both mean Avro. It returns nil when the name says nothing.
+func codecByName(path string) Codec {
+	if path == "" || path == "-" {
+		return nil
+	}
+	base := filepath.Base(path)
+	ext := strings.ToLower(filepath.Ext(base))
+	if name, ok := formatExtensions[ext]; ok {
+		return lookupCodec(name)
+	}
+	return lookupCodec(strings.TrimSuffix(base, filepath.Ext(base)))
+}
+
+// ownWireFormat reports whether c writes a format of its own; the JSON
+// engines write the same document as Json and are never detected.
+func ownWireFormat(c Codec) bool {
+	switch c.(type) {
+	case jsonEngine, easyjsonCodec:
+		return false
+	}
+	return true
+}
+
+// detectCodec finds which codec wrote data by decoding it with every
+// format. Binary formats accept a lot of garbage, so a candidate also has
+// to encode what it decoded back to the same number of bytes (maps may
+// come out in another order); an exact byte match wins over that.
+func detectCodec(data []byte) (Codec, error) {
+	var exact, sameSize, decoded []Codec
+	for _, c := range codecs {
+		if !ownWireFormat(c) {
+			continue
+		}
+		v := newModel(c)
+		if outcome, _ := robustDecodeInto(c, data, v); outcome != robustDecoded {
+			continue
+		}
+		decoded = append(decoded, c)
+		again, err := c.Marshal(modelOf(c, testOf(c, v)))
+		switch {
+		case err != nil:
+		case bytes.Equal(again, data):
+			exact = append(exact, c)
+		case len(again) == len(data):
+			sameSize = append(sameSize, c)
+		}
+	}
+	for _, found := range [][]Codec{exact, sameSize} {
+		switch len(found) {
+		case 0:
+			continue
+		case 1:
+			return found[0], nil
+		}
+		return nil, fmt.Errorf("input could be any of %s, pick one with -from", joinNames(found))
+	}
+	if len(decoded) > 0 {
+		return nil, fmt.Errorf("input decodes as %s but none of them reproduces it, pick one with -from", joinNames(decoded))
+	}
+	return nil, fmt.Errorf("no format decodes the input")
+}
+
+func joinNames(cs []Codec) string {
+	names := make([]string, len(cs))
+	for i, c := range cs {
+		names[i] = c.Name()
+	}
+	return strings.Join(names, ", ")
+}
+
+func readInput(path string) ([]byte, error) {
+	if path == "-" {
+		return ioutil.ReadAll(os.Stdin)
+	}
+	return ioutil.ReadFile(path)
+}
+
+func convertCmd(args []string) int {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	from := fs.String("from", "", "format of IN; detected from the file name or the content by default")
+	to := fs.String("to", "", "format of OUT; taken from the file name of OUT by default")
+	verify := fs.Bool("verify", true, "check that OUT decodes to the same value as IN and report what the target format lost")
+	tolerance := fs.Float64("tolerance", 1e-6, "relative tolerance for float comparison in -verify")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: main convert [flags] IN [OUT]")
+		fmt.Fprintln(fs.Output(), "converts a Test between formats; - is stdin or stdout. Without OUT only the detected format of IN is printed")
+		fmt.Fprintf(fs.Output(), "formats: %s\n", strings.Join(codecNames(), ", "))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		return 2
+	}
+	in, out := fs.Arg(0), fs.Arg(1)
+
+	for _, c := range codecs {
+		if s, ok := c.(Setuper); ok {
+			if err := s.Setup(); err != nil {
+				fmt.Fprintln(os.Stderr, c.Name(), "setup error:", err)
+				return 1
+			}
+		}
+	}
+
+	data, err := readInput(in)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	src, how := lookupCodec(*from), "-from"
+	if *from == "" {
+		if src, how = codecByName(in), "file name"; src == nil {
+			if src, err = detectCodec(data); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", in, err)
+				return 1
+			}
+			how = "content"
+		}
+	} else if src == nil {
+		fmt.Fprintf(os.Stderr, "unknown format %q (known: %s)\n", *from, strings.Join(codecNames(), ","))
+		return 2
+	}
+
+	v := newModel(src)
+	if err := src.Unmarshal(data, v); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: not %s: %v\n", in, src.Name(), err)
+		return 1
+	}
+	t := testOf(src, v)
+	if out == "" {
+		fmt.Printf("%s: %s (by %s), %d bytes\n", in, src.Name(), how, len(data))
+		return 0
+	}
+
+	dst := lookupCodec(*to)
+	if *to == "" {
+		dst = codecByName(out)
+	}
+	if dst == nil {
+		fmt.Fprintf(os.Stderr, "unknown output format, set -to (known: %s)\n", strings.Join(codecNames(), ","))
+		return 2
+	}
+	encoded, err := dst.Marshal(modelOf(dst, t))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", dst.Name(), err)
+		return 1
+	}
+	if out == "-" {
+		_, err = os.Stdout.Write(encoded)
+	} else {
+		err = ioutil.WriteFile(out, encoded, 0644)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "%s (%s by %s, %d bytes) -> %s (%s, %d bytes)\n", in, src.Name(), how, len(data), out, dst.Name(), len(encoded))
+
+	if *verify {
+		decoded := newModel(dst)
+		if err := dst.Unmarshal(encoded, decoded); err != nil {
+			fmt.Fprintf(os.Stderr, "%s cannot read its own output: %v\n", dst.Name(), err)
+			return 1
+		}
+		if diffs := verifyRoundTrip(dst, t, decoded, *tolerance); len(diffs) > 0 {
+			fmt.Fprintf(os.Stderr, "%s did not keep the value:\n", dst.Name())
+			for _, d := range diffs {
+				fmt.Fprintln(os.Stderr, "  ", d)
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+)
+
+// Corpus is a saved set of generated tests, so a benchmark can be
+// repeated on exactly the same data on another machine or commit.
+type Corpus struct {
+	Seed    int64  `json:"seed"`
+	Payload string `json:"payload"`
+	Tests   []Test `json:"tests"`
+}
+
+// generateCorpus draws n tests from a generator seeded with seed. All tests
+// are generated up front so the codecs never influence the random stream.
+func generateCorpus(seed int64, payload string, shape Shape, n int) *Corpus {
+	rnd := rand.New(rand.NewSource(seed))
+	c := &Corpus{Seed: seed, Payload: payload, Tests: make([]Test, n)}
+	for i := range c.Tests {
+		c.Tests[i] = generateTest(rnd, shape)
+	}
+	return c
+}
+
+func saveCorpus(path string, c *Corpus) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+func loadCorpus(path string) (*Corpus, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Corpus{}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, err
+	}
+	if len(c.Tests) == 0 {
+		return nil, fmt.Errorf("%s holds no tests", path)
+	}
+	return c, nil
+}
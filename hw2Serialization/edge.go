@@ -0,0 +1,244 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// edgeCase is a Test holding values integrations tend to break on.
+// Nilness cases also check that nil and empty slices and maps keep their
+// difference; elsewhere an absent Labels or Blob may come back empty.
+type edgeCase struct {
+	name    string
+	test    Test
+	nilness bool
+}
+
+func strptr(s string) *string { return &s }
+
+// edgeCases returns the edge-value corpus. Every case changes only a
+// few fields of an otherwise small Test, so an outcome points at them.
+func edgeCases() []edgeCase {
+	base := func(name string) Test {
+		return Test{ID: 1, Name: name, SomeNumericArray: []int32{1}, SomeFloatArray: []float32{1}, Tests: []TestStruct{{Some: "a", Other: "b"}}}
+	}
+	strs := func(s ...string) Test {
+		t := base(s[0])
+		t.Tests = nil
+		for i := 0; i+1 < len(s); i += 2 {
+			t.Tests = append(t.Tests, TestStruct{Some: s[i], Other: s[i+1]})
+		}
+		t.Comment = strptr(s[len(s)-1])
+		t.Labels = Labels{"k": s[0], s[len(s)-1]: "v"}
+		return t
+	}
+	nested := func(depth int) Test {
+		t := base("deep")
+		child := TestStruct{Some: "leaf"}
+		for i := 0; i < depth; i++ {
+			child = TestStruct{Some: fmt.Sprint(i), Children: []TestStruct{child}}
+		}
+		t.Tests = []TestStruct{child}
+		return t
+	}
+
+	cases := []edgeCase{
+		{name: "emoji", test: strs("😀", "👍🏽 skin tone", "🇷🇺 flag", "👨‍👩‍👧 zwj family")},
+		{name: "cjk and rtl", test: strs("日本語", "中文", "한국어", "עברית", "العربية ‏")},
+		{name: "combining marks", test: strs("é", "é", "Z̤͔ͧ̑̓ä͖̭̈̇lͮ̒ͫǧ̗͚̚o̙̔ͮ̇͐̇")},
+		{name: "invalid utf-8", test: strs("\xff\xfe", "a\xc3(b", "\xed\xa0\x80 surrogate", "truncated \xe2\x82")},
+		{name: "control chars", test: strs("\x00nul", "\x01\x07\x08\x1b\x7f", "tab\there", "cr\rlf\n", "  ")},
+		{name: "markup", test: strs("<a href=\"x\">&amp;</a>", "]]>", "&#0;", "<?xml?>", "'\"")},
+		{name: "yaml words", test: strs("yes", "no", "~", "null", "0x1F", "1e3", "- dash", "#hash", "key: value", "  padded  ")},
+		{name: "long string", test: strs(strings.Repeat("x", 1<<16), strings.Repeat("я", 1<<15))},
+		{name: "empty strings", test: strs("", "", "")},
+	}
+
+	t := base("nan and inf")
+	t.SomeFloatArray = []float32{float32(math.NaN()), float32(math.Inf(1)), float32(math.Inf(-1))}
+	cases = append(cases, edgeCase{name: "nan and inf", test: t})
+
+	t = base("float extremes")
+	t.SomeFloatArray = []float32{math.MaxFloat32, -math.MaxFloat32, math.SmallestNonzeroFloat32, 1.17549435e-38, float32(math.Copysign(0, -1)), 0.1, 16777217}
+	cases = append(cases, edgeCase{name: "float extremes", test: t})
+
+	t = base("int32 extremes")
+	t.ID = math.MinInt32
+	t.SomeNumericArray = []int32{math.MinInt32, math.MinInt32 + 1, -1, 0, math.MaxInt32}
+	cases = append(cases, edgeCase{name: "int32 extremes", test: t})
+
+	t = base("empty slices")
+	t.SomeNumericArray, t.SomeFloatArray, t.Tests = []int32{}, []float32{}, []TestStruct{}
+	t.Labels, t.Blob = Labels{}, []byte{}
+	cases = append(cases, edgeCase{name: "empty slices", test: t, nilness: true})
+
+	t = base("nil slices")
+	t.SomeNumericArray, t.SomeFloatArray, t.Tests = nil, nil, nil
+	cases = append(cases, edgeCase{name: "nil slices", test: t, nilness: true})
+
+	t = base("binary blob")
+	t.Blob = []byte{0, 0xff, '<', '&', 0x80, '\n', 0}
+	cases = append(cases, edgeCase{name: "binary blob", test: t})
+
+	cases = append(cases,
+		edgeCase{name: "zero value", test: Test{}, nilness: true},
+		edgeCase{name: "deep nesting", test: nested(100)},
+	)
+	return cases
+}
+
+// Outcomes of one edge case besides the robustPanic, robustHang and
+// robustAlloc of a decoder that misbehaved.
+const (
+	edgeOK       = "ok"       // decoded exactly as encoded
+	edgeAltered  = "altered"  // decoded into a different value
+	edgeRejected = "rejected" // refused with an error on encode or decode
+)
+
+// EdgeResult is what one codec did with one edge case.
+type EdgeResult struct {
+	Codec   string `json:"codec"`
+	Case    string `json:"case"`
+	Outcome string `json:"outcome"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+// edgeMarshal encodes with a recover, as some codecs panic on odd values.
+func edgeMarshal(c Codec, v interface{}) (data []byte, panicked interface{}, err error) {
+	defer func() {
+		panicked = recover()
+	}()
+	data, err = c.Marshal(v)
+	return data, nil, err
+}
+
+func runEdge(c Codec, ec edgeCase) EdgeResult {
+	res := EdgeResult{Codec: c.Name(), Case: ec.name}
+	data, p, err := edgeMarshal(c, modelOf(c, ec.test))
+	switch {
+	case p != nil:
+		res.Outcome, res.Detail = robustPanic, fmt.Sprintf("encode: %v", p)
+		return res
+	case err != nil:
+		res.Outcome, res.Detail = edgeRejected, fmt.Sprintf("encode: %v", err)
+		return res
+	}
+
+	v := newModel(c)
+	switch outcome, detail := robustDecodeInto(c, data, v); outcome {
+	case robustDecoded:
+	case robustError:
+		res.Outcome, res.Detail = edgeRejected, "decode: "+detail
+		return res
+	default:
+		res.Outcome, res.Detail = outcome, "decode: "+detail
+		return res
+	}
+
+	if diffs := edgeDiff(ec.test, testOf(c, v), ec.nilness); len(diffs) > 0 {
+		res.Outcome, res.Detail = edgeAltered, strings.Join(diffs, "; ")
+		return res
+	}
+	res.Outcome = edgeOK
+	return res
+}
+
+// edgeDiff is diffTest without tolerance that also tells -0 from 0 and,
+// with nilness, nil slices from empty ones, which diffTest ignores.
+func edgeDiff(want, got Test, nilness bool) []string {
+	diffs := diffTest(want, got, 0)
+	nilDiff := func(name string, w, g bool) {
+		if nilness && w != g {
+			state := map[bool]string{true: "nil", false: "empty"}
+			diffs = append(diffs, fmt.Sprintf("%s: want %s, got %s", name, state[w], state[g]))
+		}
+	}
+	if len(want.SomeNumericArray) == 0 && len(got.SomeNumericArray) == 0 {
+		nilDiff("SomeNumericArray", want.SomeNumericArray == nil, got.SomeNumericArray == nil)
+	}
+	if len(want.SomeFloatArray) == 0 && len(got.SomeFloatArray) == 0 {
+		nilDiff("SomeFloatArray", want.SomeFloatArray == nil, got.SomeFloatArray == nil)
+	}
+	if len(want.Tests) == 0 && len(got.Tests) == 0 {
+		nilDiff("Tests", want.Tests == nil, got.Tests == nil)
+	}
+	if len(want.Labels) == 0 && len(got.Labels) == 0 {
+		nilDiff("Labels", want.Labels == nil, got.Labels == nil)
+	}
+	if len(want.Blob) == 0 && len(got.Blob) == 0 {
+		nilDiff("Blob", want.Blob == nil, got.Blob == nil)
+	}
+	if len(want.SomeFloatArray) == len(got.SomeFloatArray) {
+		for i, w := range want.SomeFloatArray {
+			g := got.SomeFloatArray[i]
+			if w == 0 && g == 0 && math.Signbit(float64(w)) != math.Signbit(float64(g)) {
+				diffs = append(diffs, fmt.Sprintf("SomeFloatArray[%d]: want %v, got %v", i, signedZero(w), signedZero(g)))
+			}
+		}
+	}
+	return diffs
+}
+
+func signedZero(f float32) string {
+	if math.Signbit(float64(f)) {
+		return "-0"
+	}
+	return "0"
+}
+
+// runEdges runs every edge case through every enabled codec and prints a
+// matrix of outcomes with the details below it. The exit code is 1 when a
+// codec panicked, hung or ran out of bounds.
+func runEdges(enabled []Codec, report *Report) int {
+	cases := edgeCases()
+	// a first decode builds caches of some codecs, which would count
+	// against the allocation limit of the first case
+	for _, c := range enabled {
+		if data, err := c.Marshal(modelOf(c, Test{ID: 1, Name: "warm-up"})); err == nil {
+			c.Unmarshal(data, newModel(c))
+		}
+	}
+
+	width := 0
+	for _, ec := range cases {
+		if len(ec.name) > width {
+			width = len(ec.name)
+		}
+	}
+
+	fmt.Printf("%-*s", width+2, "")
+	for _, c := range enabled {
+		fmt.Printf(" %-9.9s", c.Name())
+	}
+	fmt.Println()
+
+	var details []EdgeResult
+	code := 0
+	for _, ec := range cases {
+		fmt.Printf("%-*s", width+2, ec.name)
+		for _, c := range enabled {
+			res := runEdge(c, ec)
+			report.Edge = append(report.Edge, res)
+			fmt.Printf(" %-9s", res.Outcome)
+			switch res.Outcome {
+			case edgeOK:
+				continue
+			case robustPanic, robustHang, robustAlloc:
+				code = 1
+			}
+			details = append(details, res)
+		}
+		fmt.Println()
+	}
+
+	fmt.Println()
+	for _, res := range details {
+		detail := res.Detail
+		if len(detail) > 300 {
+			detail = detail[:300] + "..."
+		}
+		fmt.Printf("%s, %s: %s: %s\n", res.Codec, res.Case, res.Outcome, detail)
+	}
+	return code
+}
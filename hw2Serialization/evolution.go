@@ -0,0 +1,567 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+
+	models "hw2Serialization/models"
+
+	"github.com/hamba/avro/v2"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// evoField is one field of a record version in the schema evolution matrix.
+// Every format derives its schema (Go struct tags, .proto descriptor,
+// Avro schema) from the same list, so a change means the same everywhere.
+// In a version of the real Test a field without a kind is the Test field
+// of that name, kept as test.proto and schema.avsc declare it.
+type evoField struct {
+	name  string // exported field name, used by every format for new fields
+	kind  string // int32, int64 or string; empty keeps the type of the Test field
+	tag   int32  // protobuf field number of a new field
+	alias string // former name, kept as an Avro alias after a rename
+	def   bool   // the Avro field has a default, as Avro wants for added fields
+}
+
+// evoRecord is what the versions of a record are built from: nothing but
+// their fields for the synthetic Record, the struct, .proto message and
+// Avro schema of Test, and the test the versions write, for the real one.
+type evoRecord struct {
+	name     string
+	test     *Test
+	goFields []reflect.StructField
+	proto    *descriptorpb.FileDescriptorProto
+	avro     map[string]interface{}
+}
+
+type evoVersion struct {
+	rec    *evoRecord
+	fields []evoField
+}
+
+// evoChange is one schema change: data written with old is read with new
+// (backward compatibility) and the other way round (forward compatibility).
+type evoChange struct {
+	name     string
+	old, new evoVersion
+}
+
+// syntheticChanges changes a record of three scalar fields, where every
+// format can show what a change does without the rest of Test in the way.
+func syntheticChanges() []evoChange {
+	rec := &evoRecord{name: "Record"}
+	version := func(fields ...evoField) evoVersion { return evoVersion{rec, fields} }
+	id := evoField{name: "ID", kind: "int32", tag: 1}
+	name := evoField{name: "Name", kind: "string", tag: 2}
+	count := evoField{name: "Count", kind: "int32", tag: 3}
+	base := version(id, name, count)
+	return []evoChange{
+		{"add field", base, version(id, name, count, evoField{name: "Email", kind: "string", tag: 4, def: true})},
+		{"remove field", base, version(id, name)},
+		{"rename field", base, version(id, evoField{name: "Title", kind: "string", tag: 2, alias: "Name"}, count)},
+		{"int32 to int64", base, version(id, name, evoField{name: "Count", kind: "int64", tag: 3})},
+		{"reorder fields", base, version(count, name, id)},
+	}
+}
+
+// testChanges makes the same changes to the real Test: Email is added,
+// Comment removed, Name renamed to Title, ID widened and the field order
+// reversed. Every version writes t.
+func testChanges(t Test) ([]evoChange, error) {
+	text, err := ioutil.ReadFile(avroSchemaPath)
+	if err != nil {
+		return nil, err
+	}
+	rec := &evoRecord{name: "Test", test: &t, proto: protodesc.ToFileDescriptorProto(models.File_test_proto)}
+	if err := json.Unmarshal(text, &rec.avro); err != nil {
+		return nil, fmt.Errorf("%s: %v", avroSchemaPath, err)
+	}
+	typ := reflect.TypeOf(t)
+	var base []evoField
+	for i := 0; i < typ.NumField(); i++ {
+		rec.goFields = append(rec.goFields, typ.Field(i))
+		base = append(base, evoField{name: typ.Field(i).Name})
+	}
+
+	edit := func(fn func(f evoField) (evoField, bool)) evoVersion {
+		var fields []evoField
+		for _, f := range base {
+			if f, ok := fn(f); ok {
+				fields = append(fields, f)
+			}
+		}
+		return evoVersion{rec, fields}
+	}
+	added := edit(func(f evoField) (evoField, bool) { return f, true })
+	added.fields = append(added.fields, evoField{name: "Email", kind: "string", tag: 9, def: true})
+	reversed := edit(func(f evoField) (evoField, bool) { return f, true })
+	for i, j := 0, len(reversed.fields)-1; i < j; i, j = i+1, j-1 {
+		reversed.fields[i], reversed.fields[j] = reversed.fields[j], reversed.fields[i]
+	}
+
+	old := evoVersion{rec, base}
+	return []evoChange{
+		{"add field", old, added},
+		{"remove field", old, edit(func(f evoField) (evoField, bool) { return f, f.name != "Comment" })},
+		{"rename field", old, edit(func(f evoField) (evoField, bool) {
+			if f.name == "Name" {
+				f = evoField{name: "Title", alias: "Name"}
+			}
+			return f, true
+		})},
+		{"int32 to int64", old, edit(func(f evoField) (evoField, bool) {
+			if f.name == "ID" {
+				f.kind = "int64"
+			}
+			return f, true
+		})},
+		{"reorder fields", old, reversed},
+	}, nil
+}
+
+// base returns the Test field f keeps or renames.
+func (r *evoRecord) base(f evoField) (reflect.StructField, bool) {
+	for _, sf := range r.goFields {
+		if sf.Name == f.name || sf.Name == f.alias {
+			return sf, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// avroField returns a copy of the schema.avsc field f keeps or renames;
+// Avro names of Test differ from the Go ones only in case.
+func (r *evoRecord) avroField(f evoField) (map[string]interface{}, bool) {
+	if _, ok := r.base(f); !ok {
+		return nil, false
+	}
+	fields, _ := r.avro["fields"].([]interface{})
+	for _, field := range fields {
+		field, _ := field.(map[string]interface{})
+		name, _ := field["name"].(string)
+		if strings.EqualFold(name, f.name) || strings.EqualFold(name, f.alias) {
+			return copyMap(field), true
+		}
+	}
+	return nil, false
+}
+
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+// value is what the version writes into f: the test for a kept Test
+// field, otherwise a fixed value. A widened field holds a number the
+// old type cannot.
+func (v evoVersion) value(f evoField) interface{} {
+	if f.kind == "int64" {
+		return int64(1) << 40
+	}
+	if sf, ok := v.rec.base(f); ok {
+		return reflect.ValueOf(*v.rec.test).FieldByIndex(sf.Index).Interface()
+	}
+	switch f.name {
+	case "ID":
+		return int32(42)
+	case "Count":
+		return int32(7)
+	case "Email":
+		return "reader@example.com"
+	}
+	return "evolution"
+}
+
+var evoKinds = map[string]reflect.Type{
+	"int32":  reflect.TypeOf(int32(0)),
+	"int64":  reflect.TypeOf(int64(0)),
+	"string": reflect.TypeOf(""),
+}
+
+// goType builds the struct the reflection based codecs work on. Kept
+// Test fields keep their tags; new and renamed fields are tagged with
+// their name in every format.
+func (v evoVersion) goType() reflect.Type {
+	fields := []reflect.StructField{{
+		Name: "XMLName",
+		Type: reflect.TypeOf(xml.Name{}),
+		Tag:  reflect.StructTag(fmt.Sprintf(`xml:"%s" json:"-" yaml:"-" msgpack:"-" bson:"-" cbor:"-" avro:"-"`, v.rec.name)),
+	}}
+	for _, f := range v.fields {
+		sf, ok := v.rec.base(f)
+		if !ok || f.alias != "" {
+			sf.Name = f.name
+			sf.Tag = reflect.StructTag(fmt.Sprintf(`json:"%[1]s" yaml:"%[1]s" xml:"%[1]s" msgpack:"%[1]s" bson:"%[1]s" cbor:"%[1]s" avro:"%[1]s"`, f.name))
+		}
+		if f.kind != "" {
+			sf.Type = evoKinds[f.kind]
+		}
+		fields = append(fields, reflect.StructField{Name: sf.Name, Type: sf.Type, Tag: sf.Tag})
+	}
+	return reflect.StructOf(fields)
+}
+
+// newValue returns a pointer to a goType struct filled with the written values.
+func (v evoVersion) newValue() reflect.Value {
+	p := reflect.New(v.goType())
+	for _, f := range v.fields {
+		p.Elem().FieldByName(f.name).Set(reflect.ValueOf(v.value(f)))
+	}
+	return p
+}
+
+func (v evoVersion) fromValue(p reflect.Value) map[string]interface{} {
+	got := make(map[string]interface{}, len(v.fields))
+	for _, f := range v.fields {
+		got[f.name] = p.Elem().FieldByName(f.name).Interface()
+	}
+	return got
+}
+
+func (v evoVersion) avroSchema() (avro.Schema, error) {
+	kinds := map[string]string{"int32": "int", "int64": "long", "string": "string"}
+	record := map[string]interface{}{"type": "record", "name": v.rec.name}
+	if v.rec.avro != nil {
+		record = copyMap(v.rec.avro)
+	}
+	fields := make([]map[string]interface{}, len(v.fields))
+	for i, f := range v.fields {
+		fields[i] = map[string]interface{}{"name": f.name, "type": kinds[f.kind]}
+		alias := f.alias
+		if base, ok := v.rec.avroField(f); ok {
+			alias, _ = base["name"].(string)
+			fields[i] = base
+			if f.kind != "" {
+				base["type"] = kinds[f.kind]
+			}
+		}
+		if f.alias != "" {
+			fields[i]["name"] = f.name
+			fields[i]["aliases"] = []string{alias}
+		}
+		if f.def {
+			fields[i]["default"] = reflect.Zero(evoKinds[f.kind]).Interface()
+		}
+	}
+	record["fields"] = fields
+	schema, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	// Every version has the name of its record, so they must not share
+	// the schema cache.
+	return avro.ParseWithCache(string(schema), "", &avro.SchemaCache{})
+}
+
+func (v evoVersion) protoDescriptor() (protoreflect.MessageDescriptor, error) {
+	types := map[string]descriptorpb.FieldDescriptorProto_Type{
+		"int32":  descriptorpb.FieldDescriptorProto_TYPE_INT32,
+		"int64":  descriptorpb.FieldDescriptorProto_TYPE_INT64,
+		"string": descriptorpb.FieldDescriptorProto_TYPE_STRING,
+	}
+	file := &descriptorpb.FileDescriptorProto{
+		Name:        proto.String("evolution.proto"),
+		Package:     proto.String("evolution"),
+		Syntax:      proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{Name: proto.String(v.rec.name)}},
+	}
+	if v.rec.proto != nil {
+		file = proto.Clone(v.rec.proto).(*descriptorpb.FileDescriptorProto)
+	}
+	var msg *descriptorpb.DescriptorProto
+	for _, m := range file.MessageType {
+		if m.GetName() == v.rec.name {
+			msg = m
+		}
+	}
+
+	kept := msg.Field
+	msg.Field = nil
+	for _, f := range v.fields {
+		fd := &descriptorpb.FieldDescriptorProto{
+			Name:   proto.String(f.name),
+			Number: proto.Int32(f.tag),
+			Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		}
+		for _, k := range kept {
+			if strings.EqualFold(k.GetName(), f.name) || strings.EqualFold(k.GetName(), f.alias) {
+				fd = k
+			}
+		}
+		if f.alias != "" {
+			fd.Name, fd.JsonName = proto.String(f.name), nil
+		}
+		if f.kind != "" {
+			fd.Type = types[f.kind].Enum()
+		}
+		msg.Field = append(msg.Field, fd)
+	}
+
+	// a removed proto3 optional field leaves its synthetic oneof behind
+	var decls []*descriptorpb.OneofDescriptorProto
+	index := make(map[int32]int32)
+	for _, fd := range msg.Field {
+		if fd.OneofIndex == nil {
+			continue
+		}
+		i, ok := index[fd.GetOneofIndex()]
+		if !ok {
+			i = int32(len(decls))
+			index[fd.GetOneofIndex()] = i
+			decls = append(decls, msg.OneofDecl[fd.GetOneofIndex()])
+		}
+		fd.OneofIndex = proto.Int32(i)
+	}
+	msg.OneofDecl = decls
+
+	fd, err := protodesc.NewFile(file, nil)
+	if err != nil {
+		return nil, err
+	}
+	return fd.Messages().ByName(protoreflect.Name(v.rec.name)), nil
+}
+
+// protoField finds the field of md that f is, ignoring the case in which
+// test.proto spells kept Test fields.
+func protoField(md protoreflect.MessageDescriptor, f evoField) protoreflect.FieldDescriptor {
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		if strings.EqualFold(string(fields.Get(i).Name()), f.name) {
+			return fields.Get(i)
+		}
+	}
+	return nil
+}
+
+func (v evoVersion) fromMessage(m protoreflect.Message) map[string]interface{} {
+	tree := protoTree(m)
+	got := make(map[string]interface{}, len(v.fields))
+	for _, f := range v.fields {
+		got[f.name] = tree[string(protoField(m.Descriptor(), f).Name())]
+	}
+	return got
+}
+
+// match finds the field of v that f reads from, following renames.
+func (v evoVersion) match(f evoField) (evoField, bool) {
+	for _, w := range v.fields {
+		if w.name == f.name || w.name == f.alias || w.alias == f.name {
+			return w, true
+		}
+	}
+	return evoField{}, false
+}
+
+// evoEncode writes the writer version with c. Protobuf and Avro need a
+// schema per version; every other codec works on the reflected struct.
+func evoEncode(c Codec, writer evoVersion) ([]byte, error) {
+	switch c.(type) {
+	case *protoCodec:
+		md, err := writer.protoDescriptor()
+		if err != nil {
+			return nil, err
+		}
+		m := dynamicpb.NewMessage(md)
+		if writer.rec.test != nil {
+			// kept fields carry the test over by their numbers
+			data, err := proto.Marshal(modelOf(c, *writer.rec.test).(proto.Message))
+			if err != nil {
+				return nil, err
+			}
+			if err := proto.Unmarshal(data, m); err != nil {
+				return nil, err
+			}
+			m.SetUnknown(nil)
+		}
+		for _, f := range writer.fields {
+			if f.kind != "" {
+				m.Set(protoField(md, f), protoreflect.ValueOf(writer.value(f)))
+			}
+		}
+		return proto.Marshal(m)
+	case *avroCodec:
+		schema, err := writer.avroSchema()
+		if err != nil {
+			return nil, err
+		}
+		return avro.Marshal(schema, writer.newValue().Interface())
+	}
+	return c.Marshal(writer.newValue().Elem().Interface())
+}
+
+// evoDecode reads data written with writer as the reader version. Avro
+// resolves the writer schema against the reader one, as its readers do.
+func evoDecode(c Codec, writer, reader evoVersion, data []byte) (map[string]interface{}, error) {
+	switch c.(type) {
+	case *protoCodec:
+		md, err := reader.protoDescriptor()
+		if err != nil {
+			return nil, err
+		}
+		m := dynamicpb.NewMessage(md)
+		if err := proto.Unmarshal(data, m); err != nil {
+			return nil, err
+		}
+		return reader.fromMessage(m), nil
+	case *avroCodec:
+		ws, err := writer.avroSchema()
+		if err != nil {
+			return nil, err
+		}
+		rs, err := reader.avroSchema()
+		if err != nil {
+			return nil, err
+		}
+		if writer.rec.test != nil {
+			return evoResolveTest(writer, reader, ws, rs, data)
+		}
+		resolved, err := avro.NewSchemaCompatibility().Resolve(rs, ws)
+		if err != nil {
+			return nil, err
+		}
+		p := reflect.New(reader.goType())
+		if err := avro.Unmarshal(resolved, data, p.Interface()); err != nil {
+			return nil, err
+		}
+		return reader.fromValue(p), nil
+	}
+	p := reflect.New(reader.goType())
+	if err := c.Unmarshal(data, p.Interface()); err != nil {
+		return nil, err
+	}
+	return reader.fromValue(p), nil
+}
+
+// evoResolveTest reads Avro data of one Test version as another. Resolve
+// of hamba/avro recurses forever on the recursive TestStruct, so once the
+// library found the schemas compatible the data is read with the writer
+// schema and the top-level fields are resolved here the way Avro does;
+// no change touches the nested records.
+func evoResolveTest(writer, reader evoVersion, ws, rs avro.Schema, data []byte) (map[string]interface{}, error) {
+	if err := avro.NewSchemaCompatibility().Compatible(rs, ws); err != nil {
+		return nil, err
+	}
+	w := reflect.New(writer.goType())
+	if err := avro.Unmarshal(ws, data, w.Interface()); err != nil {
+		return nil, err
+	}
+	r := reflect.New(reader.goType())
+	for _, f := range reader.fields {
+		if wf, ok := writer.match(f); ok {
+			field := r.Elem().FieldByName(f.name)
+			field.Set(w.Elem().FieldByName(wf.name).Convert(field.Type()))
+		}
+	}
+	return reader.fromValue(r), nil
+}
+
+// Compatibility outcomes of one direction of a schema change.
+const (
+	evoOK    = "ok"    // every shared field read back intact
+	evoLossy = "lossy" // decoded, but shared fields differ from what the writer reads back
+	evoError = "error" // the reader rejected the data
+	evoNA    = "n/a"   // the codec cannot encode a generic record
+)
+
+func evoCheck(c Codec, writer, reader evoVersion) (status, note string) {
+	data, err := evoEncode(c, writer)
+	if err != nil {
+		return evoNA, "cannot encode a generic record"
+	}
+	// the writer's own reading is the reference, so precision a codec
+	// loses on every read is not taken for an effect of the change
+	written, err := evoDecode(c, writer, writer, data)
+	if err != nil {
+		return evoError, "the writer cannot read its own data: " + strings.Replace(err.Error(), "\n", " ", -1)
+	}
+	got, err := evoDecode(c, writer, reader, data)
+	if err != nil {
+		return evoError, strings.Replace(err.Error(), "\n", " ", -1)
+	}
+	var lost []string
+	for _, f := range reader.fields {
+		w, ok := writer.match(f)
+		if !ok {
+			continue
+		}
+		if want, have := evoString(written[w.name]), evoString(got[f.name]); want != have {
+			lost = append(lost, fmt.Sprintf("%s: want %s, got %s", f.name, want, have))
+		}
+	}
+	if len(lost) > 0 {
+		return evoLossy, strings.Join(lost, "; ")
+	}
+	return evoOK, ""
+}
+
+// evoString prints a field value for comparison: pointers are followed,
+// numbers of both widths print alike and long values are cut short.
+func evoString(v interface{}) string {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	s := "<nil>"
+	if rv.IsValid() && rv.Kind() != reflect.Ptr {
+		s = fmt.Sprint(rv.Interface())
+	}
+	if len(s) > 60 {
+		s = s[:60] + "..."
+	}
+	return s
+}
+
+// EvolutionResult is the compatibility of one codec under one schema
+// change of Record, the synthetic three-field record, or Test. Backward is
+// the new reader on old data, Forward the old reader on new data.
+type EvolutionResult struct {
+	Codec        string `json:"codec"`
+	Record       string `json:"record"`
+	Change       string `json:"change"`
+	Backward     string `json:"backward"`
+	Forward      string `json:"forward"`
+	BackwardNote string `json:"backward_note,omitempty"`
+	ForwardNote  string `json:"forward_note,omitempty"`
+}
+
+// runEvolution fills report with the schema evolution matrix of every
+// enabled codec, for the synthetic record and for Test writing t. It
+// returns the process exit code.
+func runEvolution(enabled []Codec, t Test, report *Report) int {
+	tested, err := testChanges(t)
+	if err != nil {
+		fmt.Println("schema error:", err)
+		return 2
+	}
+	changes := append(syntheticChanges(), tested...)
+	for _, c := range enabled {
+		fmt.Printf("%s\n  %-7s %-16s %-14s %s\n", c.Name(), "record", "change", "new reads old", "old reads new")
+		for _, ch := range changes {
+			r := EvolutionResult{Codec: c.Name(), Record: ch.old.rec.name, Change: ch.name}
+			r.Backward, r.BackwardNote = evoCheck(c, ch.old, ch.new)
+			r.Forward, r.ForwardNote = evoCheck(c, ch.new, ch.old)
+			report.Evolution = append(report.Evolution, r)
+
+			fmt.Printf("  %-7s %-16s %-14s %s\n", r.Record, r.Change, r.Backward, r.Forward)
+			if r.BackwardNote != "" {
+				fmt.Printf("      new reads old: %s\n", r.BackwardNote)
+			}
+			if r.ForwardNote != "" {
+				fmt.Printf("      old reads new: %s\n", r.ForwardNote)
+			}
+		}
+		fmt.Println()
+	}
+	return 0
+}
@@ -0,0 +1,47 @@
+//go:build go1.18
+// +build go1.18
+
+package main
+
+import "testing"
+
+// fuzzUnmarshal feeds arbitrary bytes to the Unmarshal of the named codec,
+// seeded with its encodings of the benchmark payloads. Decoders may reject
+// the input but must not panic; go test -fuzz also reports hangs and
+// out-of-memory crashes. Run one target at a time, e.g.
+//
+//	go test -run '^$' -fuzz '^FuzzProto$' -fuzztime 1m
+func fuzzUnmarshal(f *testing.F, name string) {
+	c := lookupCodec(name)
+	if c == nil {
+		f.Skipf("%s is not registered in this build", name)
+	}
+	for _, p := range benchPayloads(f) {
+		data, err := c.Marshal(modelOf(c, p.test))
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		c.Unmarshal(data, newModel(c))
+	})
+}
+
+func FuzzGob(f *testing.F)             { fuzzUnmarshal(f, "Gob") }
+func FuzzXML(f *testing.F)             { fuzzUnmarshal(f, "XML") }
+func FuzzJson(f *testing.F)            { fuzzUnmarshal(f, "Json") }
+func FuzzProto(f *testing.F)           { fuzzUnmarshal(f, "Proto") }
+func FuzzAvro(f *testing.F)            { fuzzUnmarshal(f, "Avro") }
+func FuzzYAML(f *testing.F)            { fuzzUnmarshal(f, "YAML") }
+func FuzzMSG(f *testing.F)             { fuzzUnmarshal(f, "MSG") }
+func FuzzCBOR(f *testing.F)            { fuzzUnmarshal(f, "CBOR") }
+func FuzzBSON(f *testing.F)            { fuzzUnmarshal(f, "BSON") }
+func FuzzFlatBuffers(f *testing.F)     { fuzzUnmarshal(f, "FlatBuffers") }
+func FuzzCapnProto(f *testing.F)       { fuzzUnmarshal(f, "CapnProto") }
+func FuzzJsoniter(f *testing.F)        { fuzzUnmarshal(f, "Jsoniter") }
+func FuzzJsoniterFastest(f *testing.F) { fuzzUnmarshal(f, "JsoniterFastest") }
+func FuzzGoJson(f *testing.F)          { fuzzUnmarshal(f, "GoJson") }
+func FuzzSonic(f *testing.F)           { fuzzUnmarshal(f, "Sonic") }
+func FuzzSegmentio(f *testing.F)       { fuzzUnmarshal(f, "Segmentio") }
+func FuzzEasyJson(f *testing.F)        { fuzzUnmarshal(f, "EasyJson") }
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// MachineInfo describes where a report was produced, so runs from
+// different machines are not compared by accident.
+type MachineInfo struct {
+	Hostname string `json:"hostname,omitempty"`
+	OS       string `json:"os"`
+	Arch     string `json:"arch"`
+	CPUs     int    `json:"cpus"`
+	CPUModel string `json:"cpu_model,omitempty"`
+}
+
+func machineInfo() MachineInfo {
+	m := MachineInfo{OS: runtime.GOOS, Arch: runtime.GOARCH, CPUs: runtime.NumCPU()}
+	m.Hostname, _ = os.Hostname()
+	if data, err := ioutil.ReadFile("/proc/cpuinfo"); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if strings.HasPrefix(line, "model name") {
+				if i := strings.IndexByte(line, ':'); i >= 0 {
+					m.CPUModel = strings.TrimSpace(line[i+1:])
+				}
+				break
+			}
+		}
+	}
+	return m
+}
+
+// gitCommit returns the checked out commit, marked -dirty with local
+// changes, or "" outside a git work tree.
+func gitCommit() string {
+	out, err := exec.Command("git", "describe", "--always", "--dirty").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// appendHistory adds r as one line to the JSON-lines history file.
+func appendHistory(path string, r *Report) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func loadHistory(path string) ([]*Report, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var runs []*Report
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 64<<20)
+	for line := 1; sc.Scan(); line++ {
+		if len(strings.TrimSpace(sc.Text())) == 0 {
+			continue
+		}
+		r := &Report{}
+		if err := json.Unmarshal(sc.Bytes(), r); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+		runs = append(runs, r)
+	}
+	return runs, sc.Err()
+}
+
+// selectRun resolves a compare argument: an index into the history
+// (negative counts from the end, -1 is the last run) or a -report json file.
+func selectRun(history []*Report, arg string) (*Report, error) {
+	if i, err := strconv.Atoi(arg); err == nil {
+		if i < 0 {
+			i += len(history)
+		}
+		if i < 0 || i >= len(history) {
+			return nil, fmt.Errorf("run %s: history has %d runs", arg, len(history))
+		}
+		return history[i], nil
+	}
+	data, err := ioutil.ReadFile(arg)
+	if err != nil {
+		return nil, err
+	}
+	r := &Report{}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("%s: %v", arg, err)
+	}
+	return r, nil
+}
+
+// compareCmd implements `main compare [flags] [OLD NEW]` and returns the
+// exit code: 1 when NEW has a significant regression against OLD.
+func compareCmd(args []string) int {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	historyPath := fs.String("history", "history.jsonl", "history file written by -history")
+	threshold := fs.Float64("threshold", 0.05, "smallest relative change of the mean time worth reporting")
+	alpha := fs.Float64("alpha", 0.05, "significance level of the Welch t-test")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: main compare [flags] [OLD NEW]")
+		fmt.Fprintln(fs.Output(), "OLD and NEW are history indices (negative from the end) or -report json files; default -2 -1")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	sel := []string{"-2", "-1"}
+	switch fs.NArg() {
+	case 0:
+	case 2:
+		sel = fs.Args()
+	default:
+		fs.Usage()
+		return 2
+	}
+
+	var history []*Report
+	if isIndex(sel[0]) || isIndex(sel[1]) {
+		var err error
+		if history, err = loadHistory(*historyPath); err != nil {
+			fmt.Println("history error:", err)
+			return 2
+		}
+	}
+	old, err := selectRun(history, sel[0])
+	if err != nil {
+		fmt.Println(err)
+		return 2
+	}
+	cur, err := selectRun(history, sel[1])
+	if err != nil {
+		fmt.Println(err)
+		return 2
+	}
+
+	fmt.Printf("old: %s %s %s\nnew: %s %s %s\n",
+		old.Timestamp.Format("2006-01-02 15:04:05"), old.Commit, old.GoVersion,
+		cur.Timestamp.Format("2006-01-02 15:04:05"), cur.Commit, cur.GoVersion)
+	if old.Config.Seed != cur.Config.Seed || old.Config.Payload != cur.Config.Payload || old.Config.Tests != cur.Config.Tests {
+		fmt.Println("warning: runs used different test data (seed, payload or tests differ)")
+	}
+	if old.Machine != cur.Machine {
+		fmt.Println("warning: runs come from different machines")
+	}
+	fmt.Println()
+
+	regressions := 0
+	fmt.Printf("%-16s %-7s %12s %12s %8s %8s\n", "codec", "", "old mean", "new mean", "change", "p")
+	for _, n := range cur.Overall {
+		var o *Result
+		for i := range old.Overall {
+			if old.Overall[i].Codec == n.Codec {
+				o = &old.Overall[i]
+			}
+		}
+		if o == nil {
+			fmt.Printf("%-16s only in the new run\n", n.Codec)
+			continue
+		}
+		for _, m := range []struct {
+			name     string
+			old, new Stats
+		}{{"encode", o.Encode, n.Encode}, {"decode", o.Decode, n.Decode}} {
+			change := relChange(float64(m.old.Mean), float64(m.new.Mean))
+			p := welchP(m.old, m.new)
+			verdict := ""
+			if p < *alpha && change > *threshold {
+				verdict = "REGRESSION"
+				regressions++
+			} else if p < *alpha && change < -*threshold {
+				verdict = "improvement"
+			}
+			fmt.Printf("%-16s %-7s %12v %12v %+7.1f%% %8.4f %s\n", n.Codec, m.name, m.old.Mean, m.new.Mean, change*100, p, verdict)
+		}
+		if o.Size != n.Size {
+			change := relChange(float64(o.Size), float64(n.Size))
+			verdict := ""
+			if change > *threshold {
+				verdict = "REGRESSION"
+				regressions++
+			}
+			fmt.Printf("%-16s %-7s %12d %12d %+7.1f%% %8s %s\n", n.Codec, "size", o.Size, n.Size, change*100, "", verdict)
+		}
+	}
+
+	if regressions > 0 {
+		fmt.Printf("\n%d significant regressions\n", regressions)
+		return 1
+	}
+	return 0
+}
+
+func isIndex(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
+func relChange(old, new float64) float64 {
+	if old == 0 {
+		return 0
+	}
+	return (new - old) / old
+}
+
+// welchP is the two-sided p-value of Welch's t-test for equal means,
+// computed from the summaries alone. Stats keeps the population standard
+// deviation, which is corrected to the sample one here.
+func welchP(a, b Stats) float64 {
+	if a.N < 2 || b.N < 2 {
+		return 1
+	}
+	na, nb := float64(a.N), float64(b.N)
+	va := float64(a.StdDev) * float64(a.StdDev) * na / (na - 1) / na
+	vb := float64(b.StdDev) * float64(b.StdDev) * nb / (nb - 1) / nb
+	if va+vb == 0 {
+		if a.Mean == b.Mean {
+			return 1
+		}
+		return 0
+	}
+	t := (float64(b.Mean) - float64(a.Mean)) / math.Sqrt(va+vb)
+	df := (va + vb) * (va + vb) / (va*va/(na-1) + vb*vb/(nb-1))
+	return incompleteBeta(df/2, 0.5, df/(df+t*t))
+}
+
+// incompleteBeta is the regularized incomplete beta function I_x(a, b),
+// evaluated with the continued fraction from Numerical Recipes.
+func incompleteBeta(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	la, _ := math.Lgamma(a + b)
+	lb, _ := math.Lgamma(a)
+	lc, _ := math.Lgamma(b)
+	front := math.Exp(la - lb - lc + a*math.Log(x) + b*math.Log(1-x))
+	if x < (a+1)/(a+b+2) {
+		return front * betaFraction(a, b, x) / a
+	}
+	return 1 - front*betaFraction(b, a, 1-x)/b
+}
+
+func betaFraction(a, b, x float64) float64 {
+	const (
+		maxIter = 200
+		eps     = 1e-12
+		tiny    = 1e-300
+	)
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1; m <= maxIter; m++ {
+		fm := float64(m)
+		num := fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm))
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		h *= d * c
+
+		num = -(a + fm) * (a + b + fm) * x / ((a + 2*fm) * (a + 2*fm + 1))
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		del := d * c
+		h *= del
+		if math.Abs(del-1) < eps {
+			break
+		}
+	}
+	return h
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestIncompleteBeta(t *testing.T) {
+	cases := []struct {
+		a, b, x float64
+		want    float64
+	}{
+		{1, 1, 0.3, 0.3},                 // uniform: I_x(1, 1) = x
+		{3, 1, 0.5, 0.125},               // I_x(a, 1) = x^a
+		{1, 4, 0.5, 0.9375},              // I_x(1, b) = 1 - (1-x)^b
+		{2, 3, 0.4, 0.5248},              // binomial sum over 4 trials with p = 0.4
+		{7.5, 7.5, 0.5, 0.5},             // symmetric around 1/2
+		{5, 0.5, 0.9, 0.316642915020014}, // numeric integration of the beta density
+		{2, 3, 0, 0},
+		{2, 3, 1, 1},
+	}
+	for _, c := range cases {
+		if got := incompleteBeta(c.a, c.b, c.x); math.Abs(got-c.want) > 1e-9 {
+			t.Errorf("I_%v(%v, %v): got %v, want %v", c.x, c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func statsOf(samples ...float64) Stats {
+	d := make([]time.Duration, len(samples))
+	for i, s := range samples {
+		d[i] = time.Duration(s * float64(time.Millisecond))
+	}
+	return computeStats(d)
+}
+
+func TestWelchP(t *testing.T) {
+	cases := []struct {
+		name string
+		a, b Stats
+		want float64
+	}{
+		// the two examples of Welch's t-test on Wikipedia: t = -2.46 with
+		// 24.99 degrees of freedom and t = -1.57 with 9.90
+		{
+			"equal sizes",
+			statsOf(27.5, 21.0, 19.0, 23.6, 17.0, 17.9, 16.9, 20.1, 21.9, 22.6, 23.1, 19.6, 19.0, 21.7, 21.4),
+			statsOf(27.1, 22.0, 20.8, 23.4, 23.4, 23.5, 25.8, 22.0, 24.8, 20.2, 21.9, 22.1, 22.9, 20.5, 24.4),
+			0.021378,
+		},
+		{
+			"unequal sizes",
+			statsOf(17.2, 20.9, 22.6, 18.1, 21.7, 21.4, 23.5, 24.2, 14.7, 21.8),
+			statsOf(21.5, 22.8, 21.0, 23.0, 21.6, 23.6, 22.5, 20.7, 23.4, 21.8, 20.7, 21.7, 21.5, 22.5, 23.6, 21.5, 22.5, 23.5, 21.5, 21.8),
+			0.148842,
+		},
+		{"one sample", statsOf(1), statsOf(2, 3), 1},
+		{"no spread, same mean", statsOf(5, 5, 5), statsOf(5, 5), 1},
+		{"no spread, other mean", statsOf(5, 5, 5), statsOf(6, 6), 0},
+	}
+	for _, c := range cases {
+		if got := welchP(c.a, c.b); math.Abs(got-c.want) > 1e-5 {
+			t.Errorf("%s: got p = %v, want %v", c.name, got, c.want)
+		}
+		if got, want := welchP(c.b, c.a), welchP(c.a, c.b); got != want {
+			t.Errorf("%s: p depends on the order: %v and %v", c.name, got, want)
+		}
+	}
+}
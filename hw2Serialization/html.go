@@ -0,0 +1,228 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// Chart geometry shared by every SVG in the HTML report.
+const (
+	chartWidth  = 760
+	chartLabel  = 140 // left column with codec names
+	chartRight  = 90  // room for value labels
+	chartRow    = 22
+	chartTop    = 30
+	chartBottom = 30
+)
+
+var htmlPage = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Serialization benchmark</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin: 1em 0; }
+th, td { border: 1px solid #ccc; padding: 3px 8px; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+.failed { color: #c00; font-weight: bold; }
+svg { display: block; margin: 1em 0; }
+svg text { font-size: 12px; }
+</style>
+</head>
+<body>
+<h1>Serialization benchmark</h1>
+<p>{{.Report.Timestamp.Format "2006-01-02 15:04:05 MST"}}, {{.Report.GoVersion}}{{with .Report.Commit}}, commit {{.}}{{end}},
+{{.Report.Machine.OS}}/{{.Report.Machine.Arch}} {{.Report.Machine.CPUs}} CPUs{{with .Report.Machine.CPUModel}} ({{.}}){{end}}.<br>
+{{.Report.Config.Tests}} tests x {{.Report.Config.Runs}} runs (warm-up {{.Report.Config.Warmup}}), payload {{.Report.Config.Payload}}, seed {{.Report.Config.Seed}}.</p>
+
+<h2>Size</h2>
+{{.Size}}
+<h2>Encode latency</h2>
+{{.Encode}}
+<h2>Decode latency</h2>
+{{.Decode}}
+<h2>Size vs speed</h2>
+{{.Scatter}}
+
+<h2>Overall</h2>
+<table>
+<tr><th>Codec</th><th>Size, bytes</th><th>Encode median</th><th>Encode p99</th><th>Decode median</th><th>Decode p99</th><th>Encode B/op</th><th>Decode B/op</th><th>Fidelity</th></tr>
+{{range .Report.Overall}}<tr><td>{{.Codec}}</td><td>{{.Size}}</td><td>{{.Encode.Median}}</td><td>{{.Encode.P99}}</td><td>{{.Decode.Median}}</td><td>{{.Decode.P99}}</td><td>{{printf "%.0f" .EncodeMem.BytesPerOp}}</td><td>{{printf "%.0f" .DecodeMem.BytesPerOp}}</td><td>{{if .FidelityOK}}ok{{else}}<span class="failed">failed</span>{{end}}</td></tr>
+{{end}}</table>
+{{with .Report.Config.ReuseEncodeOnly}}<p>{{range $i, $name := .}}{{if $i}}, {{end}}{{$name}}{{end}} reuse state on encode only and decode like their naive codec.</p>
+{{end}}</body>
+</html>
+`))
+
+// writeHTML renders r as one self-contained page with inline SVG charts
+// and no external resources, so it can be published as a build artifact.
+func writeHTML(w io.Writer, r *Report) error {
+	if r.Config.Stream > 0 || r.Config.Parallel > 0 || r.Config.Robustness > 0 || r.Config.Evolution || r.Config.Interop || r.Config.Sizes || r.Config.Edge {
+		return fmt.Errorf("the html report is only available for the regular benchmark")
+	}
+	return htmlPage.Execute(w, struct {
+		Report                        *Report
+		Size, Encode, Decode, Scatter template.HTML
+	}{
+		Report:  r,
+		Size:    sizeChart(r.Overall),
+		Encode:  latencyChart(r.Overall, func(res Result) Stats { return res.Encode }),
+		Decode:  latencyChart(r.Overall, func(res Result) Stats { return res.Decode }),
+		Scatter: scatterChart(r.Overall),
+	})
+}
+
+func svgOpen(b *strings.Builder, height int) {
+	fmt.Fprintf(b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`, chartWidth, height, chartWidth, height)
+}
+
+func svgText(b *strings.Builder, x, y float64, anchor, text string) {
+	fmt.Fprintf(b, `<text x="%.1f" y="%.1f" text-anchor="%s">%s</text>`, x, y, anchor, template.HTMLEscapeString(text))
+}
+
+// sizeChart is a horizontal bar per codec.
+func sizeChart(rows []Result) template.HTML {
+	var b strings.Builder
+	height := chartTop + len(rows)*chartRow + 10
+	svgOpen(&b, height)
+
+	max := 1
+	for _, res := range rows {
+		if res.Size > max {
+			max = res.Size
+		}
+	}
+	width := float64(chartWidth - chartLabel - chartRight)
+	for i, res := range rows {
+		y := float64(chartTop + i*chartRow)
+		svgText(&b, chartLabel-6, y+14, "end", res.Codec)
+		bar := width * float64(res.Size) / float64(max)
+		fmt.Fprintf(&b, `<rect x="%d" y="%.1f" width="%.1f" height="16" fill="#4e79a7"/>`, chartLabel, y+2, bar)
+		svgText(&b, float64(chartLabel)+bar+4, y+14, "start", fmt.Sprintf("%d B", res.Size))
+	}
+	b.WriteString("</svg>")
+	return template.HTML(b.String())
+}
+
+// logScale maps durations onto [x0, x0+width] logarithmically, with
+// bounds rounded out to powers of ten for the ticks.
+type logScale struct {
+	lo, hi    float64
+	x0, width float64
+}
+
+func newLogScale(min, max time.Duration, x0, width float64) logScale {
+	if min < 1 {
+		min = 1
+	}
+	if max <= min {
+		max = min * 10
+	}
+	return logScale{
+		lo:    math.Floor(math.Log10(float64(min))),
+		hi:    math.Ceil(math.Log10(float64(max))),
+		x0:    x0,
+		width: width,
+	}
+}
+
+func (s logScale) x(d time.Duration) float64 {
+	v := math.Log10(math.Max(float64(d), 1))
+	return s.x0 + (v-s.lo)/(s.hi-s.lo)*s.width
+}
+
+func (s logScale) ticks(b *strings.Builder, top, bottom float64) {
+	for p := s.lo; p <= s.hi; p++ {
+		d := time.Duration(math.Pow(10, p))
+		x := s.x(d)
+		fmt.Fprintf(b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#ddd"/>`, x, top, x, bottom)
+		svgText(b, x, bottom+16, "middle", d.String())
+	}
+}
+
+// latencyChart draws the distribution of one operation per codec on a
+// log axis: a line from min to max, a box from median to p95 and a tick
+// at p99.
+func latencyChart(rows []Result, pick func(Result) Stats) template.HTML {
+	var b strings.Builder
+	height := chartTop + len(rows)*chartRow + chartBottom
+	svgOpen(&b, height)
+
+	var min, max time.Duration = math.MaxInt64, 0
+	for _, res := range rows {
+		s := pick(res)
+		if s.Min < min {
+			min = s.Min
+		}
+		if s.Max > max {
+			max = s.Max
+		}
+	}
+	bottom := float64(chartTop + len(rows)*chartRow)
+	scale := newLogScale(min, max, chartLabel, chartWidth-chartLabel-chartRight)
+	scale.ticks(&b, chartTop, bottom)
+
+	for i, res := range rows {
+		s := pick(res)
+		y := float64(chartTop+i*chartRow) + 11
+		svgText(&b, chartLabel-6, y+4, "end", res.Codec)
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#888"/>`, scale.x(s.Min), y, scale.x(s.Max), y)
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="12" fill="#f28e2b"/>`,
+			scale.x(s.Median), y-6, math.Max(scale.x(s.P95)-scale.x(s.Median), 1))
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#222" stroke-width="2"/>`, scale.x(s.P99), y-6, scale.x(s.P99), y+6)
+		svgText(&b, chartWidth-chartRight+6, y+4, "start", s.Median.String())
+	}
+	svgText(&b, chartLabel, 16, "start", "line: min..max, box: median..p95, tick: p99, label: median")
+	b.WriteString("</svg>")
+	return template.HTML(b.String())
+}
+
+// scatterChart places every codec by median encode+decode time (log x)
+// and size (linear y); the best codecs end up bottom left.
+func scatterChart(rows []Result) template.HTML {
+	const height = 420
+	var b strings.Builder
+	svgOpen(&b, height)
+
+	left, top, bottom := 70.0, float64(chartTop), float64(height-chartBottom)
+	var min, max time.Duration = math.MaxInt64, 0
+	maxSize := 1
+	for _, res := range rows {
+		t := res.Encode.Median + res.Decode.Median
+		if t < min {
+			min = t
+		}
+		if t > max {
+			max = t
+		}
+		if res.Size > maxSize {
+			maxSize = res.Size
+		}
+	}
+	scale := newLogScale(min, max, left, chartWidth-left-chartRight)
+	scale.ticks(&b, top, bottom)
+	y := func(size int) float64 { return bottom - (bottom-top)*float64(size)/(float64(maxSize)*1.1) }
+	for i := 0; i <= 4; i++ {
+		size := int(float64(maxSize) * 1.1 * float64(i) / 4)
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#eee"/>`, left, y(size), float64(chartWidth-chartRight), y(size))
+		svgText(&b, left-6, y(size)+4, "end", fmt.Sprintf("%d B", size))
+	}
+
+	for _, res := range rows {
+		cx, cy := scale.x(res.Encode.Median+res.Decode.Median), y(res.Size)
+		color := "#59a14f"
+		if !res.FidelityOK {
+			color = "#e15759"
+		}
+		fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="5" fill="%s"/>`, cx, cy, color)
+		svgText(&b, cx+8, cy+4, "start", res.Codec)
+	}
+	svgText(&b, left, 16, "start", "x: median encode + decode time, y: size; red: failed verification")
+	b.WriteString("</svg>")
+	return template.HTML(b.String())
+}
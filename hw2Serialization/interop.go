@@ -0,0 +1,415 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/hamba/avro/v2"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const protoSchemaPath = "test.proto"
+
+// genericFormat decodes artifacts of one codec without the generated or
+// hand-written Go types, only from the checked-in schema, the way a
+// reader in another language would.
+type genericFormat struct {
+	schema string
+	// decode returns the generic value and its canonical tree.
+	decode func(data []byte) (interface{}, interface{}, error)
+	// encode writes a generic value back, so the typed codec can check
+	// that nothing was lost on the way.
+	encode func(v interface{}) ([]byte, error)
+}
+
+// genericFormats are the codecs with an interop check, by codec name.
+var genericFormats = map[string]func() (*genericFormat, error){
+	"Proto": protoGeneric,
+	"Avro":  avroGeneric,
+}
+
+// protoGeneric compiles test.proto at run time and decodes with dynamicpb,
+// so the descriptor compiled into models/test.pb.go is not involved.
+func protoGeneric() (*genericFormat, error) {
+	md, err := compileProtoMessage(protoSchemaPath, "Test")
+	if err != nil {
+		return nil, err
+	}
+	return &genericFormat{
+		schema: protoSchemaPath,
+		decode: func(data []byte) (interface{}, interface{}, error) {
+			m := dynamicpb.NewMessage(md)
+			if err := proto.Unmarshal(data, m); err != nil {
+				return nil, nil, err
+			}
+			return m, protoTree(m), nil
+		},
+		encode: func(v interface{}) ([]byte, error) {
+			return proto.MarshalOptions{Deterministic: true}.Marshal(v.(proto.Message))
+		},
+	}, nil
+}
+
+// compileProtoMessage parses a .proto file, with imports resolved next to
+// it, and returns the message called name, or the first one when name is
+// empty.
+func compileProtoMessage(path, name string) (protoreflect.MessageDescriptor, error) {
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			ImportPaths: []string{filepath.Dir(path)},
+		}),
+	}
+	files, err := compiler.Compile(context.Background(), filepath.Base(path))
+	if err != nil {
+		return nil, err
+	}
+	msgs := files[0].Messages()
+	if name == "" && msgs.Len() > 0 {
+		return msgs.Get(0), nil
+	}
+	for i := 0; i < msgs.Len(); i++ {
+		if md := msgs.Get(i); string(md.Name()) == name || string(md.FullName()) == name {
+			return md, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: no message %s", path, name)
+}
+
+// protoTree lists every field of m by its .proto name: unset optional
+// fields are null, unset proto3 scalars have their zero value and enums
+// are written by name.
+func protoTree(m protoreflect.Message) map[string]interface{} {
+	out := make(map[string]interface{})
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		name := string(fd.Name())
+		v := m.Get(fd)
+		switch {
+		case fd.IsList():
+			list := v.List()
+			items := make([]interface{}, list.Len())
+			for j := range items {
+				items[j] = protoValue(fd, list.Get(j))
+			}
+			out[name] = items
+		case fd.IsMap():
+			entries := make(map[string]interface{})
+			v.Map().Range(func(k protoreflect.MapKey, mv protoreflect.Value) bool {
+				entries[k.String()] = protoValue(fd.MapValue(), mv)
+				return true
+			})
+			out[name] = entries
+		case fd.HasPresence() && !m.Has(fd):
+			out[name] = nil
+		default:
+			out[name] = protoValue(fd, v)
+		}
+	}
+	return out
+}
+
+func protoValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) interface{} {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return protoTree(v.Message())
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+		return int32(v.Enum())
+	}
+	return v.Interface()
+}
+
+// avroGeneric decodes with models/schema.avsc into maps and slices
+// instead of the tagged Test struct.
+func avroGeneric() (*genericFormat, error) {
+	text, err := ioutil.ReadFile(avroSchemaPath)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := avro.ParseWithCache(string(text), "", &avro.SchemaCache{})
+	if err != nil {
+		return nil, err
+	}
+	return &genericFormat{
+		schema: avroSchemaPath,
+		decode: func(data []byte) (interface{}, interface{}, error) {
+			var v interface{}
+			if err := avro.Unmarshal(schema, data, &v); err != nil {
+				return nil, nil, err
+			}
+			return v, avroTree(schema, v), nil
+		},
+		encode: func(v interface{}) ([]byte, error) {
+			return avro.Marshal(schema, v)
+		},
+	}, nil
+}
+
+// avroTree follows the schema to unwrap unions to their bare value, as
+// the other formats have no notion of the union branch.
+func avroTree(s avro.Schema, v interface{}) interface{} {
+	switch s := s.(type) {
+	case *avro.RefSchema:
+		return avroTree(s.Schema(), v)
+	case *avro.RecordSchema:
+		m, _ := v.(map[string]interface{})
+		out := make(map[string]interface{}, len(s.Fields()))
+		for _, f := range s.Fields() {
+			out[f.Name()] = avroTree(f.Type(), m[f.Name()])
+		}
+		return out
+	case *avro.ArraySchema:
+		items, _ := v.([]interface{})
+		out := make([]interface{}, len(items))
+		for i, item := range items {
+			out[i] = avroTree(s.Items(), item)
+		}
+		return out
+	case *avro.MapSchema:
+		m, _ := v.(map[string]interface{})
+		out := make(map[string]interface{}, len(m))
+		for k, item := range m {
+			out[k] = avroTree(s.Values(), item)
+		}
+		return out
+	case *avro.UnionSchema:
+		branch, ok := v.(map[string]interface{})
+		if v == nil || !ok || len(branch) != 1 {
+			return v
+		}
+		for name, item := range branch {
+			for _, t := range s.Types() {
+				if avroTypeName(t) == name {
+					return avroTree(t, item)
+				}
+			}
+		}
+	}
+	return v
+}
+
+func avroTypeName(s avro.Schema) string {
+	if n, ok := s.(avro.NamedSchema); ok {
+		return n.FullName()
+	}
+	return string(s.Type())
+}
+
+// canonicalJSON writes a decoded tree as canonical JSON: object keys
+// sorted, two space indent, bytes in padded standard base64, 64 bit
+// integers as plain numbers, floats in the shortest form that reads back
+// to the same float32 or float64, and NaN and infinities as the strings
+// "NaN", "Infinity" and "-Infinity", like protojson.
+func canonicalJSON(tree interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(canonicalValue(tree)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func canonicalValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			out[k] = canonicalValue(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = canonicalValue(item)
+		}
+		return out
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v)
+	case float32:
+		return canonicalFloat(float64(v), 32)
+	case float64:
+		return canonicalFloat(v, 64)
+	}
+	return v
+}
+
+func canonicalFloat(f float64, bits int) interface{} {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+	return json.Number(strconv.FormatFloat(f, 'g', -1, bits))
+}
+
+// InteropResult is the outcome of reading one codec's artifact with
+// nothing but its schema. Outcome is "ok" when the generic decoder read
+// the artifact and its value, written back, decodes to the original test;
+// Golden compares the canonical JSON with the golden file.
+type InteropResult struct {
+	Codec     string `json:"codec"`
+	Schema    string `json:"schema"`
+	Artifact  string `json:"artifact"`
+	Canonical string `json:"canonical"`
+	Outcome   string `json:"outcome"`
+	Golden    string `json:"golden,omitempty"`
+	Note      string `json:"note,omitempty"`
+}
+
+// Golden file states.
+const (
+	goldenMatch   = "match"
+	goldenDiffers = "differs"
+	goldenMissing = "missing"
+	goldenUpdated = "updated"
+)
+
+// runInterop writes t to files/<codec> with every enabled codec that has
+// a generic decoder, reads it back with that decoder and writes the
+// canonical JSON next to it. With golden set the JSON is compared with
+// golden/<codec>.json, or with update the artifact and its JSON are
+// copied there. It returns the process exit code.
+func runInterop(enabled []Codec, t Test, tol float64, golden string, update bool, report *Report) int {
+	code := 0
+	for _, c := range enabled {
+		load, ok := genericFormats[c.Name()]
+		if !ok {
+			continue
+		}
+		res := interopCheck(c, load, t, tol, golden, update)
+		report.Interop = append(report.Interop, res)
+
+		fmt.Printf("%-6s %-8s %s -> %s", c.Name(), res.Outcome, res.Artifact, res.Canonical)
+		if res.Golden != "" {
+			fmt.Printf(", golden %s", res.Golden)
+		}
+		fmt.Println()
+		if res.Note != "" {
+			fmt.Printf("  %s\n", res.Note)
+		}
+		if res.Outcome != "ok" || res.Golden == goldenDiffers || res.Golden == goldenMissing {
+			code = 1
+		}
+	}
+	if len(report.Interop) == 0 {
+		fmt.Println("no selected format has a generic decoder (Proto, Avro)")
+		return 2
+	}
+	return code
+}
+
+func interopCheck(c Codec, load func() (*genericFormat, error), t Test, tol float64, golden string, update bool) InteropResult {
+	res := InteropResult{Codec: c.Name(), Artifact: artifactPath(c), Canonical: artifactPath(c) + ".json", Outcome: "error"}
+	g, err := load()
+	if err != nil {
+		res.Note = err.Error()
+		return res
+	}
+	res.Schema = g.schema
+
+	data, err := c.Marshal(modelOf(c, t))
+	if err == nil {
+		err = ioutil.WriteFile(res.Artifact, data, 0644)
+	}
+	if err == nil {
+		data, err = ioutil.ReadFile(res.Artifact)
+	}
+	if err != nil {
+		res.Note = err.Error()
+		return res
+	}
+
+	v, tree, err := g.decode(data)
+	if err != nil {
+		res.Note = "generic decode: " + err.Error()
+		return res
+	}
+	canonical, err := canonicalJSON(tree)
+	if err == nil {
+		err = ioutil.WriteFile(res.Canonical, canonical, 0644)
+	}
+	if err != nil {
+		res.Note = err.Error()
+		return res
+	}
+
+	again, err := g.encode(v)
+	if err != nil {
+		res.Note = "generic encode: " + err.Error()
+		return res
+	}
+	decoded := newModel(c)
+	if err := c.Unmarshal(again, decoded); err != nil {
+		res.Note = "typed decode of the generic value: " + err.Error()
+		return res
+	}
+	if diffs := verifyRoundTrip(c, t, decoded, tol); len(diffs) > 0 {
+		res.Outcome = "mismatch"
+		res.Note = fmt.Sprint(diffs)
+		return res
+	}
+	res.Outcome = "ok"
+
+	if golden != "" {
+		res.Golden, err = checkGolden(golden, c.Name(), data, canonical, update)
+		if err != nil {
+			res.Note = err.Error()
+		}
+	}
+	return res
+}
+
+// checkGolden compares canonical with golden/<name>.json, or with update
+// stores the artifact and its canonical JSON there.
+func checkGolden(dir, name string, artifact, canonical []byte, update bool) (string, error) {
+	path := filepath.Join(dir, name+".json")
+	if update {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return "", err
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name), artifact, 0644); err != nil {
+			return "", err
+		}
+		return goldenUpdated, ioutil.WriteFile(path, canonical, 0644)
+	}
+	want, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return goldenMissing, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if !bytes.Equal(want, canonical) {
+		return goldenDiffers, fmt.Errorf("%s: first difference on line %d", path, firstDiffLine(want, canonical))
+	}
+	return goldenMatch, nil
+}
+
+func firstDiffLine(a, b []byte) int {
+	line := 1
+	for i := 0; i < len(a) && i < len(b) && a[i] == b[i]; i++ {
+		if a[i] == '\n' {
+			line++
+		}
+	}
+	return line
+}
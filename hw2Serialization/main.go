@@ -1,680 +1,644 @@
 package main
 
 import (
-	"bytes"
-	"encoding/gob"
-	"encoding/json"
 	"encoding/xml"
 	"flag"
 	"fmt"
 	"io/ioutil"
-	"math/rand"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
 	"time"
-	"unicode"
-
-	models "./models"
-
-	"github.com/hamba/avro"
-	"github.com/vmihailenco/msgpack"
-	"google.golang.org/protobuf/proto"
-	yaml "gopkg.in/yaml.v2"
 )
 
 type TestStruct struct {
-	Some  string `json:"some" avro:"some"`
-	Other string `json:"other" avro:"other"`
+	Some     string       `json:"some" avro:"some"`
+	Other    string       `json:"other" avro:"other"`
+	Children []TestStruct `json:"children,omitempty" yaml:"children,omitempty" msgpack:",omitempty" avro:"children"`
 }
 
+//easyjson:json
 type Test struct {
 	ID               int32        `json:"id" avro:"Id"`
 	Name             string       `json:"name" avro:"Name"`
 	SomeNumericArray []int32      `json:"somenumericarray" avro:"Somenumericarray"`
 	SomeFloatArray   []float32    `json:"somefloatarray" avro:"Somefloatarray"`
 	Tests            []TestStruct `json:"tests" avro:"Tests"`
+	Comment          *string      `json:"comment,omitempty" yaml:"comment,omitempty" xml:",omitempty" msgpack:",omitempty" avro:"Comment"`
+	Labels           Labels       `json:"labels,omitempty" yaml:"labels,omitempty" xml:",omitempty" msgpack:",omitempty" avro:"Labels"`
+	Blob             []byte       `json:"blob,omitempty" yaml:"blob,omitempty" xml:",omitempty" msgpack:",omitempty" avro:"Blob"`
 }
 
-func randString() string {
-	var letters = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789абвгдеёжзийклмнопрстуфхцчшщъыьэюяАБВГДЕЁЖЗИЙКЛМНОПРСТУФХЦЧШЩЪЫЬЭЮЯ")
-	s := make([]rune, rand.Intn(32))
-	for i := range s {
-		s[i] = letters[rand.Intn(len(letters))]
-		for i == 0 && unicode.IsDigit(s[i]) {
-			s[i] = letters[rand.Intn(len(letters))]
-		}
-	}
-	return string(s)
-}
-
-func generateTest(simple bool) (Test, models.Test) {
-	arrInt := make([]int32, rand.Intn(256))
-	len := rand.Intn(256)
-	arrTestStruct := make([]TestStruct, len)
-	arrTestTestStruct := make([]*models.TestStruct, len)
-	arrFloat := make([]float32, rand.Intn(256))
-
-	if simple {
-		arrInt = make([]int32, rand.Intn(3))
-		len = rand.Intn(3)
-		arrTestStruct = make([]TestStruct, len)
-		arrTestTestStruct = make([]*models.TestStruct, len)
-		arrFloat = make([]float32, rand.Intn(3))
-	}
+// Labels is a plain string map that encoding/xml can also handle.
+type Labels map[string]string
 
-	for i := range arrInt {
-		arrInt[i] = rand.Int31()
+func (l Labels) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	keys := make([]string, 0, len(l))
+	for k := range l {
+		keys = append(keys, k)
 	}
+	sort.Strings(keys)
 
-	for i := range arrTestStruct {
-		str := randString()
-		str1 := randString()
-		arrTestStruct[i] = TestStruct{Some: str, Other: str1}
-		arrTestTestStruct[i] = &models.TestStruct{Some: str, Other: str1}
+	if err := e.EncodeToken(start); err != nil {
+		return err
 	}
-	for i := range arrFloat {
-		arrFloat[i] = rand.Float32()
+	for _, k := range keys {
+		label := xml.StartElement{Name: xml.Name{Local: "label"}, Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: k}}}
+		if err := e.EncodeElement(l[k], label); err != nil {
+			return err
+		}
 	}
+	return e.EncodeToken(start.End())
+}
 
-	t := Test{
-		ID:               rand.Int31(),
-		Name:             randString(),
-		Tests:            arrTestStruct,
-		SomeFloatArray:   arrFloat,
-		SomeNumericArray: arrInt,
+func (l *Labels) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var entries struct {
+		Label []struct {
+			Key   string `xml:"key,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"label"`
+	}
+	if err := d.DecodeElement(&entries, &start); err != nil {
+		return err
 	}
-	pt := models.Test{
-		ID:               t.ID,
-		Name:             t.Name,
-		SomeNumericArray: arrInt,
-		Tests:            arrTestTestStruct,
-		SomeFloatArray:   arrFloat,
+	*l = make(Labels, len(entries.Label))
+	for _, e := range entries.Label {
+		(*l)[e.Key] = e.Value
 	}
-	return t, pt
+	return nil
 }
 
-func XMLSerialise(t Test, silence bool) (int, int, int) {
+// serialise runs one marshal/unmarshal round of c over v on in-memory buffers.
+// With persist the encoded bytes also go through files/ and the disk
+// write/read cost is measured separately from the codec itself, and every
+// compressor in comps is run over the encoded bytes.
+func serialise(c Codec, v interface{}, comps []Compressor, persist, silence bool) runResult {
+	var r runResult
 
 	if !silence {
-		fmt.Println("before serilize:\n", t)
+		fmt.Println("before serilize:\n", v)
 	}
-	var buff bytes.Buffer
 
-	start := time.Now().Nanosecond()
+	var m0, m1 runtime.MemStats
 
-	encoder := xml.NewEncoder(&buff)
-	errors := encoder.Encode(t)
-
-	end := time.Now().Nanosecond()
-	elapsed := end - start
+	runtime.ReadMemStats(&m0)
+	start := time.Now()
+	out, errors := c.Marshal(v)
+	r.encode = time.Since(start)
+	runtime.ReadMemStats(&m1)
+	r.encodeMem = memDelta(&m0, &m1)
 
 	if errors != nil {
-		fmt.Println("ERRS", errors)
+		fmt.Println("encoding error", errors)
+		r.err = fmt.Errorf("encoding error: %v", errors)
 	}
-	ioutil.WriteFile("files/XML", buff.Bytes(), 0644)
 
-	size := len(buff.Bytes())
+	r.size = len(out)
+	r.data = out
 
 	if !silence {
-		fmt.Printf("Serialized: \n%X\n size: %d\n", buff.Bytes(), size)
-	}
-
-	start = time.Now().Nanosecond()
-
-	var out Test
-	file, errors := ioutil.ReadFile("files/XML")
-	decoder := xml.NewDecoder(bytes.NewBuffer(file))
-	errors = decoder.Decode(&out)
-
-	end = time.Now().Nanosecond()
-	elapsed1 := end - start
-
-	if errors != nil {
-		fmt.Println("ERRS", errors)
+		fmt.Printf("Serialized: \n%X\n size: %d\n", out, r.size)
 	}
 
-	if !silence {
-		fmt.Println("Deserialized: \n", out, "\n")
-
+	if r.err == nil && len(comps) > 0 {
+		var err error
+		if r.compressed, err = compressAll(comps, out); err != nil {
+			fmt.Println(err)
+			r.err = err
+		}
 	}
 
-	return size, elapsed, elapsed1
-}
+	if persist {
+		start = time.Now()
+		errors = ioutil.WriteFile(artifactPath(c), out, 0644)
+		r.write = time.Since(start)
+		if errors != nil {
+			fmt.Println("writing error", errors)
+		}
 
-func GobSerialise(t Test, silence bool) (int, int, int) {
-	if !silence {
-		fmt.Println("before serilize:\n", t)
+		start = time.Now()
+		out, errors = ioutil.ReadFile(artifactPath(c))
+		r.read = time.Since(start)
+		if errors != nil {
+			fmt.Println("reading error", errors)
+		}
 	}
 
-	start := time.Now().Nanosecond()
-
-	var buff bytes.Buffer
-	encoder := gob.NewEncoder(&buff)
-	errors := encoder.Encode(t)
+	result := newModel(c)
 
-	end := time.Now().Nanosecond()
-	elapsed := end - start
+	runtime.ReadMemStats(&m0)
+	start = time.Now()
+	errors = c.Unmarshal(out, result)
+	r.decode = time.Since(start)
+	runtime.ReadMemStats(&m1)
+	r.decodeMem = memDelta(&m0, &m1)
 
 	if errors != nil {
-		fmt.Println("ERRS", errors)
+		fmt.Println("decoding error", errors)
+		if r.err == nil {
+			r.err = fmt.Errorf("decoding error: %v", errors)
+		}
 	}
-
-	ioutil.WriteFile("files/Gob", buff.Bytes(), 0644)
-
-	size := len(buff.Bytes())
+	r.decoded = result
 
 	if !silence {
-		fmt.Printf("Serialized: \n%X\n size: %d\n", buff.Bytes(), size)
+		fmt.Print("Deserialized: \n", result, "\n\n")
 	}
 
-	start = time.Now().Nanosecond()
-
-	file, errors := ioutil.ReadFile("files/Gob")
-	decoder := gob.NewDecoder(bytes.NewBuffer(file))
-	var out Test
-	errors = decoder.Decode(&out)
-
-	end = time.Now().Nanosecond()
-	elapsed1 := end - start
+	return r
+}
 
-	if errors != nil {
-		fmt.Println("ERRS", errors)
+// checkRun verifies the decoded value of r against the original test t.
+func checkRun(c Codec, t Test, r runResult, tol float64) []string {
+	if r.err != nil {
+		return []string{r.err.Error()}
 	}
-
-	if !silence {
-		fmt.Println("Deserialized: \n", out, "\n")
-
+	if r.decoded == nil {
+		return []string{"nothing was decoded, so nothing could be checked"}
 	}
-
-	return size, elapsed, elapsed1
+	return verifyRoundTrip(c, t, r.decoded, tol)
 }
 
-func JsonSerialise(t Test, silence bool) (int, int, int) {
-	if !silence {
-		fmt.Println("before serilize:\n", t)
-	}
-
-	start := time.Now().Nanosecond()
+func artifactPath(c Codec) string {
+	return "files/" + c.Name()
+}
 
-	jsbytes, errors := json.Marshal(t)
+// runResult is a single measured round of one codec.
+type runResult struct {
+	size           int
+	data           []byte
+	encode, decode time.Duration
+	write, read    time.Duration
+	encodeMem      memUsage
+	decodeMem      memUsage
+	compressed     []compressRun
+	decoded        interface{}
+	err            error
+}
 
-	end := time.Now().Nanosecond()
-	elapsed := end - start
+// totals collects every measured sample of one codec.
+type totals struct {
+	size                int
+	inTime, outTime     []time.Duration
+	writeTime, readTime []time.Duration
+	encodeMem           []memUsage
+	decodeMem           []memUsage
+	encodePeak          uint64
+	decodePeak          uint64
+	compressed          []compressTotals
+}
 
-	if errors != nil {
-		fmt.Println("encoding error", errors)
+func newTotals(comps []Compressor) *totals {
+	s := &totals{compressed: make([]compressTotals, len(comps))}
+	for i, c := range comps {
+		s.compressed[i].name = c.Name()
 	}
+	return s
+}
 
-	ioutil.WriteFile("files/Json", jsbytes, 0644)
-	size := len(jsbytes)
-
-	if !silence {
-		fmt.Printf("Serialized: \n%X\n size: %d\n", jsbytes, size)
+func (s *totals) add(r runResult) {
+	s.inTime = append(s.inTime, r.encode)
+	s.outTime = append(s.outTime, r.decode)
+	s.writeTime = append(s.writeTime, r.write)
+	s.readTime = append(s.readTime, r.read)
+	s.encodeMem = append(s.encodeMem, r.encodeMem)
+	s.decodeMem = append(s.decodeMem, r.decodeMem)
+	for i, cr := range r.compressed {
+		c := &s.compressed[i]
+		c.size = cr.size
+		c.compress = append(c.compress, cr.compress)
+		c.decompress = append(c.decompress, cr.decompress)
 	}
+}
 
-	start = time.Now().Nanosecond()
+// measurePeaks encodes and decodes v once more, outside the timed runs and
+// each on its own, to find their heap high-water marks.
+func (s *totals) measurePeaks(c Codec, v interface{}) {
+	if !measurePeakHeap {
+		return
+	}
+	var data []byte
+	encode := peakHeap(func() { data, _ = c.Marshal(v) })
+	decode := peakHeap(func() { c.Unmarshal(data, newModel(c)) })
+	s.encodePeak = max(s.encodePeak, encode)
+	s.decodePeak = max(s.decodePeak, decode)
+}
 
-	file, errors := ioutil.ReadFile("files/Json")
-	result := Test{}
-	errors = json.Unmarshal(file, &result)
+func (s *totals) merge(o *totals) {
+	s.size += o.size
+	s.encodePeak = max(s.encodePeak, o.encodePeak)
+	s.decodePeak = max(s.decodePeak, o.decodePeak)
+	s.inTime = append(s.inTime, o.inTime...)
+	s.outTime = append(s.outTime, o.outTime...)
+	s.writeTime = append(s.writeTime, o.writeTime...)
+	s.readTime = append(s.readTime, o.readTime...)
+	s.encodeMem = append(s.encodeMem, o.encodeMem...)
+	s.decodeMem = append(s.decodeMem, o.decodeMem...)
+	for i := range o.compressed {
+		c := &s.compressed[i]
+		c.size += o.compressed[i].size
+		c.compress = append(c.compress, o.compressed[i].compress...)
+		c.decompress = append(c.decompress, o.compressed[i].decompress...)
+	}
+}
 
-	end = time.Now().Nanosecond()
-	elapsed1 := end - start
+func (s *totals) result(test int, codec string, persist, fidelityOK bool) Result {
+	r := Result{
+		Test:       test,
+		Codec:      codec,
+		Size:       s.size,
+		Encode:     computeStats(s.inTime),
+		Decode:     computeStats(s.outTime),
+		EncodeMem:  summariseMem(s.encodeMem, s.encodePeak),
+		DecodeMem:  summariseMem(s.decodeMem, s.decodePeak),
+		FidelityOK: fidelityOK,
+	}
+	if persist {
+		write, read := computeStats(s.writeTime), computeStats(s.readTime)
+		r.Write, r.Read = &write, &read
+	}
+	for i := range s.compressed {
+		r.Compression = append(r.Compression, s.compressed[i].result(s.size))
+	}
+	return r
+}
 
-	if errors != nil {
-		fmt.Println("decoding error", errors)
+func (s *totals) print(persist bool) {
+	fmt.Printf(" Serialize:   %v\n              %v\n Deserialize: %v\n              %v\n",
+		computeStats(s.inTime), summariseMem(s.encodeMem, s.encodePeak), computeStats(s.outTime), summariseMem(s.decodeMem, s.decodePeak))
+	if persist {
+		fmt.Printf(" Disk write:  %v\n Disk read:   %v\n", computeStats(s.writeTime), computeStats(s.readTime))
 	}
-
-	if !silence {
-		fmt.Println("Deserialized: \n", result, "\n")
+	for i := range s.compressed {
+		c := s.compressed[i].result(s.size)
+		fmt.Printf(" +%s: size %d (ratio %.2f)\n   compress:   %v\n   decompress: %v\n", c.Algorithm, c.Size, c.Ratio, c.Compress, c.Decompress)
 	}
-
-	return size, elapsed, elapsed1
 }
 
-func ProtoSerialise(t models.Test, silence bool) (int, int, int) {
-	if !silence {
-		fmt.Println("before serilize:\n", t)
+// emitReport writes the -report, if one was asked for.
+func emitReport(report *Report, format, out string) {
+	if format == "" {
+		return
 	}
-
-	start := time.Now().Nanosecond()
-
-	out, errors := proto.Marshal(&models.Test{
-		ID:               int32(t.ID),
-		Name:             t.Name,
-		Tests:            t.Tests,
-		SomeNumericArray: t.SomeNumericArray,
-	})
-
-	end := time.Now().Nanosecond()
-	elapsed := end - start
-
-	if errors != nil {
-		fmt.Println("encoding error", errors)
+	if err := writeReport(report, format, out); err != nil {
+		fmt.Println("report error:", err)
+		os.Exit(1)
 	}
+}
 
-	ioutil.WriteFile("files/Proto", out, 0644)
-	size := len(out)
-
-	if !silence {
-		fmt.Printf("Serialized: \n%X\n size: %d\n", out, size)
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "compare":
+			os.Exit(compareCmd(os.Args[2:]))
+		case "convert":
+			os.Exit(convertCmd(os.Args[2:]))
+		case "serve":
+			os.Exit(serveCmd(os.Args[2:]))
+		case "robustness-child":
+			os.Exit(robustChildCmd(os.Args[2:]))
+		}
 	}
 
-	start = time.Now().Nanosecond()
+	nruns := flag.Int("runs", 1, "number of runs for every test(int)")
+	nwarmup := flag.Int("warmup", 3, "number of unmeasured warm-up runs before every test(int)")
+	ntestsPtr := flag.Int("tests", 1, "number of tests")
+	s := flag.Bool("s", false, "show every run report(bool)")
+	si := flag.Bool("si", false, "show detail report about every run run(bool)")
+	simplePtr := flag.Bool("simpleTest", false, "all arrays in test struct have less then 4 elements (same as -payload simple)")
+	payload := flag.String("payload", "full", "payload shape: full, simple or a YAML/JSON shape file")
+	seed := flag.Int64("seed", 0, "seed for the test generator (0 picks one from the clock and prints it)")
+	corpusIn := flag.String("corpus", "", "run on tests loaded from a corpus file instead of generating them")
+	corpusOut := flag.String("save-corpus", "", "save the generated tests to a corpus file")
+	formats := flag.String("formats", "all", "comma separated list of formats to run, e.g. json,proto,avro; all leaves out the lossy JsoniterFastest")
+	stream := flag.Int("stream", 0, "stream N generated records through every format that supports it instead of the regular benchmark")
+	parallel := flag.Int("parallel", 0, "measure round-trip throughput of every format with 1, 2, 4, ... up to N workers instead of the regular benchmark")
+	parallelTime := flag.Duration("parallel-time", time.Second, "how long every -parallel measurement runs")
+	robustness := flag.Int("robustness", 0, "feed N truncated, bit flipped and length corrupted inputs per mutation to every decoder instead of benchmarking")
+	evolution := flag.Bool("evolution", false, "print the schema evolution compatibility matrix of every format instead of benchmarking(bool)")
+	schemaProto := flag.String("schema-proto", "", "benchmark random messages of this .proto file instead of Test")
+	schemaMessage := flag.String("schema-message", "", "message of -schema-proto to generate (the first one by default)")
+	schemaAvro := flag.String("schema-avro", "", "benchmark random records of this Avro schema instead of Test, or also encode the -schema-proto messages with it")
+	sizes := flag.Bool("sizes", false, "attribute the encoded bytes of every format to the fields of Test instead of benchmarking(bool)")
+	edge := flag.Bool("edge", false, "round-trip emoji, invalid UTF-8, control characters, NaN/Inf, int32 extremes, nil and empty slices through every format and report which reject or alter them instead of benchmarking(bool)")
+	interop := flag.Bool("interop", false, "write the Proto and Avro artifacts of the first test, decode them with generic decoders from test.proto and models/schema.avsc and emit canonical JSON instead of benchmarking(bool)")
+	golden := flag.String("golden", "", "directory with golden artifacts and canonical JSON to compare -interop output with")
+	updateGolden := flag.Bool("update-golden", false, "write the -interop artifacts and canonical JSON to the -golden directory instead of comparing(bool)")
+	compress := flag.String("compress", "", "comma separated compression stages to run after every format: gzip,zstd,snappy,lz4,brotli or all")
+	persistPtr := flag.Bool("persist", false, "also write/read every run through files/ and report disk cost separately(bool)")
+	reuse := flag.Bool("reuse", false, "also benchmark steady-state variants of Json, Proto, MSG, CBOR, BSON and FlatBuffers that reuse buffers, encoders and decoders between runs, next to the naive ones(bool)")
+	verifyPtr := flag.Bool("verify", true, "check that every codec decodes exactly what it encoded(bool)")
+	tolerance := flag.Float64("tolerance", 1e-6, "relative tolerance for float comparison in -verify")
+	reportFormat := flag.String("report", "", "also emit a structured report: json, csv, markdown or html")
+	historyPath := flag.String("history", "", "append this run to a JSON-lines history file for the compare subcommand")
+	reportOut := flag.String("out", "", "file to write the -report to (stdout by default, the rest of the output then goes to stderr)")
+	profileCodec := flag.String("profile", "", "codec to profile; only this codec runs while profiling")
+	cpuProfile := flag.String("cpuprofile", "", "write a CPU profile of the -profile codec to file")
+	memProfile := flag.String("memprofile", "", "write an allocation profile of the -profile codec to file")
+	memProfileRate := flag.Int("memprofilerate", 0, "set runtime.MemProfileRate for -memprofile (0 keeps the default)")
+	flag.BoolVar(&measurePeakHeap, "peak-heap", true, "measure the heap high-water mark of one extra encode and decode per test and format, each after a full GC(bool)")
+	flag.Parse()
 
-	file, errors := ioutil.ReadFile("files/Proto")
-	result := &models.Test{}
-	errors = proto.Unmarshal(file, result)
+	if *reportFormat != "" && *reportOut == "" {
+		// the report goes to stdout alone, so -report json > out.json
+		// parses; everything printed on the way goes to stderr
+		os.Stdout = os.Stderr
+	}
 
-	end = time.Now().Nanosecond()
-	elapsed1 := end - start
+	num_runs := *nruns
+	num_warmup := *nwarmup
+	silence := !*s
+	silenceInside := !*si
+	ntests := *ntestsPtr
+	persist := *persistPtr
+	verify := *verifyPtr
 
-	if errors != nil {
-		fmt.Println("decoding error", errors)
+	if *updateGolden && *golden == "" {
+		fmt.Println("-update-golden needs -golden <dir> to write to")
+		os.Exit(2)
 	}
 
-	if !silence {
-		fmt.Println("Deserialized: \n", result, "\n")
+	if ntests < 1 {
+		fmt.Println("-tests must be at least 1")
+		os.Exit(2)
 	}
 
-	return size, elapsed, elapsed1
-}
-
-func AvroSerialise(t Test, silence bool) (int, int, int) {
-	if !silence {
-		fmt.Println("before serilize:\n", t)
+	if num_runs < 1 {
+		fmt.Println("-runs must be at least 1")
+		os.Exit(2)
 	}
 
-	start := time.Now().Nanosecond()
-
-	schemaStr, errors := ioutil.ReadFile("./models/schema.avsc")
-
-	if errors != nil {
-		fmt.Println("schema reading error", errors)
+	if *profileCodec != "" {
+		*formats = *profileCodec
+		// the extra calls and collections would end up in the profiles
+		measurePeakHeap = false
+	} else if *cpuProfile != "" || *memProfile != "" {
+		fmt.Println("-cpuprofile and -memprofile need -profile <codec>")
+		os.Exit(2)
 	}
 
-	schema, errors := avro.Parse(string(schemaStr))
-
-	if errors != nil {
-		fmt.Println("schema parsing error", errors)
+	if *simplePtr {
+		*payload = "simple"
 	}
-
-	out, errors := avro.Marshal(schema, t)
-
-	if errors != nil {
-		fmt.Println("Serializing error", errors)
+	var corpus *Corpus
+	var shape Shape
+	if *corpusIn != "" {
+		if *schemaProto != "" || *schemaAvro != "" {
+			fmt.Println("-schema-proto and -schema-avro generate their messages from -payload and -seed and cannot use -corpus")
+			os.Exit(2)
+		}
+		if *stream > 0 {
+			fmt.Println("-stream generates its records from -payload and -seed and cannot use -corpus")
+			os.Exit(2)
+		}
+		c, err := loadCorpus(*corpusIn)
+		if err != nil {
+			fmt.Println("corpus error:", err)
+			os.Exit(2)
+		}
+		corpus = c
+		ntests = len(corpus.Tests)
+		fmt.Printf("Loaded %d tests from %s (seed %d, payload %s)\n", ntests, *corpusIn, corpus.Seed, corpus.Payload)
+	} else {
+		var err error
+		shape, err = loadShape(*payload)
+		if err != nil {
+			fmt.Println("payload error:", err)
+			os.Exit(2)
+		}
+		if *seed == 0 {
+			*seed = time.Now().UnixNano()
+		}
+		corpus = generateCorpus(*seed, *payload, shape, ntests)
+		fmt.Printf("Seed: %d\n", *seed)
 	}
 
-	end := time.Now().Nanosecond()
-	elapsed := end - start
-
-	ioutil.WriteFile("files/Avro", out, 0644)
-
-	size := len(out)
-
-	if !silence {
-		fmt.Printf("Serialized: \n%X\n size: %d\n", out, size)
+	if *corpusOut != "" {
+		if err := saveCorpus(*corpusOut, corpus); err != nil {
+			fmt.Println("corpus error:", err)
+			os.Exit(1)
+		}
 	}
 
-	start = time.Now().Nanosecond()
-
-	file, errors := ioutil.ReadFile("files/Avro")
-	result := &Test{}
-	errors = avro.Unmarshal(schema, file, result)
-	if errors != nil {
-		fmt.Println("decoding error", errors)
+	enabled, err := selectCodecs(*formats)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
 	}
 
-	end = time.Now().Nanosecond()
-	elapsed1 := end - start
-
-	if !silence {
-		fmt.Println("Deserialized: \n", result, "\n")
+	comps, err := selectCompressors(*compress)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
 	}
 
-	return size, elapsed, elapsed1
-}
-
-func YAMLSerialise(t Test, silence bool) (int, int, int) {
-	if !silence {
-		fmt.Println("before serilize:\n", t)
+	for _, c := range enabled {
+		if s, ok := c.(Setuper); ok {
+			if err := s.Setup(); err != nil {
+				fmt.Println(c.Name(), "setup error:", err)
+				os.Exit(1)
+			}
+		}
 	}
 
-	start := time.Now().Nanosecond()
-
-	yamlbytes, errors := yaml.Marshal(t)
-
-	if errors != nil {
-		fmt.Println("encoding error", errors)
+	report := newReport(ReportConfig{
+		Runs:    num_runs,
+		Warmup:  num_warmup,
+		Tests:   ntests,
+		Payload: corpus.Payload,
+		Seed:    corpus.Seed,
+		Corpus:  *corpusIn,
+		Persist: persist,
+		Formats: make([]string, 0, len(enabled)),
+	})
+	for _, c := range enabled {
+		report.Config.Formats = append(report.Config.Formats, c.Name())
 	}
-
-	end := time.Now().Nanosecond()
-	elapsed := end - start
-
-	ioutil.WriteFile("files/YAML", yamlbytes, 0644)
-
-	size := len(yamlbytes)
-
-	if !silence {
-		fmt.Printf("Serialized: \n%X\n size: %d\n", yamlbytes, size)
+	for _, c := range comps {
+		report.Config.Compress = append(report.Config.Compress, c.Name())
 	}
 
-	start = time.Now().Nanosecond()
-
-	file, errors := ioutil.ReadFile("files/YAML")
-	result := Test{}
-	errors = yaml.Unmarshal(file, &result)
-
-	end = time.Now().Nanosecond()
-	elapsed1 := end - start
-
-	if errors != nil {
-		fmt.Println("decoding error", errors)
+	if *evolution {
+		report.Config.Evolution = true
+		code := runEvolution(enabled, corpus.Tests[0], report)
+		emitReport(report, *reportFormat, *reportOut)
+		os.Exit(code)
 	}
 
-	if !silence {
-		fmt.Println("Deserialized: \n", result, "\n")
+	if *schemaProto != "" || *schemaAvro != "" {
+		src, err := loadSchemaSource(*schemaProto, *schemaMessage, *schemaAvro)
+		if err != nil {
+			fmt.Println("schema error:", err)
+			os.Exit(2)
+		}
+		report.Config.Schema = src.name
+		code := runSchema(enabled, src, shape, corpus.Seed, ntests, num_runs, num_warmup, report)
+		emitReport(report, *reportFormat, *reportOut)
+		os.Exit(code)
 	}
 
-	return size, elapsed, elapsed1
-}
-
-func MSGpSerialise(t Test, silence bool) (int, int, int) {
-	if !silence {
-		fmt.Println("before serilize:\n", t)
+	if *sizes {
+		report.Config.Sizes = true
+		runSizes(enabled, corpus.Tests, report)
+		emitReport(report, *reportFormat, *reportOut)
+		return
 	}
 
-	start := time.Now().Nanosecond()
-
-	msg, errors := msgpack.Marshal(t)
-
-	if errors != nil {
-		fmt.Println("encoding error", errors)
+	if *edge {
+		report.Config.Edge = true
+		code := runEdges(enabled, report)
+		emitReport(report, *reportFormat, *reportOut)
+		os.Exit(code)
 	}
 
-	end := time.Now().Nanosecond()
-	elapsed := end - start
-
-	ioutil.WriteFile("files/MSG", msg, 0644)
-
-	size := len(msg)
-
-	if !silence {
-		fmt.Printf("Serialized: \n%X\n size: %d\n", msg, size)
+	if *interop {
+		report.Config.Interop = true
+		code := runInterop(enabled, corpus.Tests[0], *tolerance, *golden, *updateGolden, report)
+		emitReport(report, *reportFormat, *reportOut)
+		os.Exit(code)
 	}
 
-	start = time.Now().Nanosecond()
-
-	file, errors := ioutil.ReadFile("files/MSG")
-	result := Test{}
-	errors = msgpack.Unmarshal(file, &result)
-
-	end = time.Now().Nanosecond()
-	elapsed1 := end - start
-	if errors != nil {
-		fmt.Println("decoding error", errors)
+	if *robustness > 0 {
+		report.Config.Robustness = *robustness
+		code := runRobustness(enabled, corpus.Tests[0], corpus.Seed, *robustness, report)
+		emitReport(report, *reportFormat, *reportOut)
+		os.Exit(code)
 	}
 
-	if !silence {
-		fmt.Println("Deserialized: \n", result, "\n")
+	if *parallel > 0 {
+		report.Config.Parallel = *parallel
+		code := runParallels(enabled, corpus.Tests, *parallel, num_warmup, *parallelTime, report)
+		emitReport(report, *reportFormat, *reportOut)
+		os.Exit(code)
 	}
 
-	return size, elapsed, elapsed1
-}
-
-func main() {
+	if *stream > 0 {
+		report.Config.Stream = *stream
+		code := runStreams(enabled, shape, corpus.Seed, *stream, verify, *tolerance, report)
+		emitReport(report, *reportFormat, *reportOut)
+		os.Exit(code)
+	}
 
-	nruns := flag.Int("runs", 1, "number of runs for every test(int)")
-	ntestsPtr := flag.Int("tests", 1, "number of tests")
-	s := flag.Bool("s", false, "show every run report(bool)")
-	si := flag.Bool("si", false, "show detail report about every run run(bool)")
-	simplePtr := flag.Bool("simpleTest", false, "all arrays in test struct have less then 4 elements")
-	flag.Parse()
+	if *reuse {
+		report.Config.Reuse = true
+		enabled = withReuse(enabled)
+		report.Config.Formats = report.Config.Formats[:0]
+		for _, c := range enabled {
+			report.Config.Formats = append(report.Config.Formats, c.Name())
+		}
+		report.Config.ReuseEncodeOnly = reuseEncodeOnly(enabled)
+		if only := report.Config.ReuseEncodeOnly; len(only) > 0 {
+			fmt.Printf("%s reuse state on encode only and decode like their naive codec\n", strings.Join(only, ", "))
+		}
+	}
 
-	num_runs := *nruns
-	silence := !*s
-	silenceInside := !*si
-	simple := *simplePtr
-	ntests := *ntestsPtr
+	failures := make(map[string]int)
+	overall := make(map[string]*totals)
+	for _, c := range enabled {
+		overall[c.Name()] = newTotals(comps)
+	}
 
-	Gob := make(map[string]int)
-	Gob["size"] = 0
-	Gob["inTime"] = 0
-	Gob["outTime"] = 0
-
-	XML := make(map[string]int)
-	XML["size"] = 0
-	XML["inTime"] = 0
-	XML["outTime"] = 0
-
-	JSON := make(map[string]int)
-	JSON["size"] = 0
-	JSON["inTime"] = 0
-	JSON["outTime"] = 0
-
-	Proto := make(map[string]int)
-	Proto["size"] = 0
-	Proto["inTime"] = 0
-	Proto["outTime"] = 0
-
-	Avro := make(map[string]int)
-	Avro["size"] = 0
-	Avro["inTime"] = 0
-	Avro["outTime"] = 0
-
-	YAML := make(map[string]int)
-	YAML["size"] = 0
-	YAML["inTime"] = 0
-	YAML["outTime"] = 0
-
-	MSG := make(map[string]int)
-	MSG["size"] = 0
-	MSG["inTime"] = 0
-	MSG["outTime"] = 0
+	prof := &profiler{cpuPath: *cpuProfile, memPath: *memProfile}
+	if err := prof.start(*memProfileRate); err != nil {
+		fmt.Println("profile error:", err)
+		os.Exit(1)
+	}
 
 	for j := 0; j < ntests; j++ {
 
 		fmt.Printf("=============================================Test=#%d=================================================\n", j)
 
-		t, tproto := generateTest(simple)
+		t := corpus.Tests[j]
 
-		GOBsize, GOBIntime, GOBOuttime := 0, 0, 0
+		for _, c := range enabled {
+			v := modelOf(c, t)
 
-		for i := 0; i < num_runs; i++ {
-			if !silence || !silenceInside {
-				fmt.Printf("------------Gob------RUN #%d---------------- \n", i)
+			for i := 0; i < num_warmup; i++ {
+				serialise(c, v, comps, persist, true)
 			}
-			F, S, T := GobSerialise(t, silenceInside)
-			GOBsize = F
-			if !silence {
-				fmt.Printf("GOB size:   %d bytes, Serialize: %d nanosec, Deserialize: %d nanosec\n Total time: %d\n", F, S, T, S+T)
-			}
-			GOBIntime += S
-			GOBOuttime += T
-		}
-
-		GOBIntime /= num_runs
-		GOBOuttime /= num_runs
-
-		Gob["size"] += GOBsize
-		Gob["inTime"] += GOBIntime
-		Gob["outTime"] += GOBOuttime
-
-		fmt.Printf("Avarage: ")
-		fmt.Printf("GOB size:   %d bytes, Serialize: %d nanosec, Deserialize: %d nanosec\n Total time: %d", GOBsize, GOBIntime, GOBOuttime, GOBIntime+GOBOuttime)
 
-		println("\n\n")
-
-		XMLsize, XMLIntime, XMLOuttime := 0, 0, 0
-
-		for i := 0; i < num_runs; i++ {
-			if !silence || !silenceInside {
-				fmt.Printf("------------XML------RUN #%d---------------- \n", i)
+			sum := newTotals(comps)
+			var last runResult
+
+			for i := 0; i < num_runs; i++ {
+				if !silence || !silenceInside {
+					fmt.Printf("------------%s------RUN #%d---------------- \n", c.Name(), i)
+				}
+				last = serialise(c, v, comps, persist, silenceInside)
+				if !silence {
+					fmt.Printf("%s size:   %d bytes, Serialize: %v, Deserialize: %v\n Total time: %v\n", c.Name(), last.size, last.encode, last.decode, last.encode+last.decode)
+					if persist {
+						fmt.Printf(" Disk write: %v, Disk read: %v\n", last.write, last.read)
+					}
+				}
+				sum.add(last)
 			}
-
-			F, S, T := XMLSerialise(t, silenceInside)
-			XMLsize = F
-			if !silence {
-				fmt.Printf("XML size:   %d bytes, Serialize: %d nanosec, Deserialize: %d nanosec\n Total time: %d\n", F, S, T, S+T)
+			sum.size = last.size
+			sum.measurePeaks(c, v)
+
+			// Keep the artifact of the last run on disk even when the disk is not measured.
+			// Reuse variants write the same bytes as their codec.
+			if _, reused := c.(*reuseCodec); !persist && !reused && last.data != nil {
+				if err := ioutil.WriteFile(artifactPath(c), last.data, 0644); err != nil {
+					fmt.Println("writing error", err)
+				}
 			}
-			XMLIntime += S
-			XMLOuttime += T
-		}
-
-		XMLIntime /= num_runs
-		XMLOuttime /= num_runs
-
-		XML["size"] += XMLsize
-		XML["inTime"] += XMLIntime
-		XML["outTime"] += XMLOuttime
-
-		fmt.Printf("Avarage: ")
-		fmt.Printf("XML size:   %d bytes, Serialize: %d nanosec, Deserialize: %d nanosec\n Total time: %d", XMLsize, XMLIntime, XMLOuttime, XMLIntime+XMLOuttime)
 
-		println("\n\n")
-
-		Jsonsize, JsonIntime, JsonOuttime := 0, 0, 0
-
-		for i := 0; i < num_runs; i++ {
-
-			if !silence || !silenceInside {
-				fmt.Printf("------------JSON------RUN #%d---------------- \n", i)
-			}
-			F, S, T := JsonSerialise(t, silenceInside)
-			Jsonsize = F
-			if !silence {
-				fmt.Printf("Json size:   %d bytes, Serialize: %d nanosec, Deserialize: %d nanosec\n Total time: %d\n", F, S, T, S+T)
+			fidelityOK := true
+			if verify {
+				if diffs := checkRun(c, t, last, *tolerance); len(diffs) > 0 {
+					fidelityOK = false
+					failures[c.Name()]++
+					fmt.Printf("%s FIDELITY FAILURE on test #%d:\n", c.Name(), j)
+					for _, d := range diffs {
+						fmt.Println("  ", d)
+					}
+				}
 			}
-			JsonIntime += S
-			JsonOuttime += T
-		}
-
-		JsonIntime /= num_runs
-		JsonOuttime /= num_runs
-
-		JSON["size"] += Jsonsize
-		JSON["inTime"] += JsonIntime
-		JSON["outTime"] += JsonOuttime
 
-		fmt.Printf("Avarage: ")
-		fmt.Printf("JSON size:  %d bytes, Serialize: %d nanosec, Deserialize: %d nanosec\n Total time: %d", Jsonsize, JsonIntime, JsonOuttime, JsonIntime+JsonOuttime)
-		println("\n\n")
+			overall[c.Name()].merge(sum)
+			report.Results = append(report.Results, sum.result(j, c.Name(), persist, fidelityOK))
 
-		protoSize, protoInTime, protoOutTime := 0, 0, 0
-
-		for i := 0; i < num_runs; i++ {
-			if !silence || !silenceInside {
-				fmt.Printf("------------Proto------RUN #%d---------------- \n", i)
-			}
-			F, S, T := ProtoSerialise(tproto, silenceInside)
-			protoSize = F
-			if !silence {
-				fmt.Printf("Proto size:   %d bytes, Serialize: %d nanosec, Deserialize: %d nanosec\n Total time: %d", F, S, T, S+T)
-			}
-			protoInTime += S
-			protoOutTime += T
+			fmt.Printf("%s size:   %d bytes\n", c.Name(), sum.size)
+			sum.print(persist)
+			fmt.Println()
 		}
+	}
 
-		protoInTime /= num_runs
-		protoOutTime /= num_runs
-
-		Proto["size"] += protoSize
-		Proto["inTime"] += protoInTime
-		Proto["outTime"] += protoOutTime
+	if err := prof.stop(); err != nil {
+		fmt.Println("profile error:", err)
+		os.Exit(1)
+	}
 
-		fmt.Printf("Avarage: ")
-		fmt.Printf("Proto size:   %d bytes, Serialize: %d nanosec, Deserialize: %d nanosec\n Total time: %d\n", protoSize, protoInTime, protoOutTime, protoInTime+protoOutTime)
-		println("\n\n")
+	for _, c := range enabled {
+		fmt.Printf("Overall %s\n Sum size: %d\n", c.Name(), overall[c.Name()].size)
+		overall[c.Name()].print(persist)
+		report.Overall = append(report.Overall, overall[c.Name()].result(-1, c.Name(), persist, failures[c.Name()] == 0))
+	}
 
-		AvroSize, AvroInTime, AvroOutTime := 0, 0, 0
+	emitReport(report, *reportFormat, *reportOut)
 
-		for i := 0; i < num_runs; i++ {
-			if !silence || !silenceInside {
-				fmt.Printf("------------Avro------RUN #%d---------------- \n", i)
-			}
-			F, S, T := AvroSerialise(t, silenceInside)
-			AvroSize = F
-			if !silence {
-				fmt.Printf("Avro size:   %d bytes, Serialize: %d nanosec, Deserialize: %d nanosec\n Total time: %d", F, S, T, S+T)
-			}
-			AvroInTime += S
-			AvroOutTime += T
+	if *historyPath != "" {
+		if err := appendHistory(*historyPath, report); err != nil {
+			fmt.Println("history error:", err)
+			os.Exit(1)
 		}
+	}
 
-		AvroInTime /= num_runs
-		AvroOutTime /= num_runs
-
-		Avro["size"] += AvroSize
-		Avro["inTime"] += AvroInTime
-		Avro["outTime"] += AvroOutTime
-
-		fmt.Printf("Avarage: ")
-		fmt.Printf("Avro size:   %d bytes, Serialize: %d nanosec, Deserialize: %d nanosec\n Total time: %d\n", AvroSize, AvroInTime, AvroOutTime, AvroInTime+AvroOutTime)
-		println("\n\n")
-
-		YAMLSize, YAMLInTime, YAMLOutTime := 0, 0, 0
-
-		for i := 0; i < num_runs; i++ {
-			if !silence || !silenceInside {
-				fmt.Printf("------------YAML------RUN #%d---------------- \n", i)
+	if verify {
+		fmt.Println()
+		failed := false
+		for _, c := range enabled {
+			if n := failures[c.Name()]; n > 0 {
+				failed = true
+				fmt.Printf("Fidelity %s: FAILED on %d of %d tests\n", c.Name(), n, ntests)
+			} else {
+				fmt.Printf("Fidelity %s: OK\n", c.Name())
 			}
-			F, S, T := YAMLSerialise(t, silenceInside)
-			YAMLSize = F
-			if !silence {
-				fmt.Printf("YAML size:   %d bytes, Serialize: %d nanosec, Deserialize: %d nanosec\n Total time: %d", F, S, T, S+T)
-			}
-			YAMLInTime += S
-			YAMLOutTime += T
 		}
-
-		YAMLInTime /= num_runs
-		YAMLOutTime /= num_runs
-
-		YAML["size"] += YAMLSize
-		YAML["inTime"] += YAMLInTime
-		YAML["outTime"] += YAMLOutTime
-
-		fmt.Printf("Avarage: ")
-		fmt.Printf("YAML size:   %d bytes, Serialize: %d nanosec, Deserialize: %d nanosec\n Total time: %d\n", YAMLSize, YAMLInTime, YAMLOutTime, YAMLInTime+YAMLOutTime)
-		println("\n\n")
-
-		MSGpSize, MSGpInTime, MSGpOutTime := 0, 0, 0
-
-		for i := 0; i < num_runs; i++ {
-			if !silence || !silenceInside {
-				fmt.Printf("------------MSGPack------RUN #%d---------------- \n", i)
-			}
-			F, S, T := MSGpSerialise(t, silenceInside)
-			MSGpSize = F
-			if !silence {
-				fmt.Printf("MSGPack size:   %d bytes, Serialize: %d nanosec, Deserialize: %d nanosec\n Total time: %d", F, S, T, S+T)
-			}
-			MSGpInTime += S
-			MSGpOutTime += T
+		if failed {
+			os.Exit(1)
 		}
-
-		MSGpInTime /= num_runs
-		MSGpOutTime /= num_runs
-
-		MSG["size"] += MSGpSize
-		MSG["inTime"] += MSGpInTime
-		MSG["outTime"] += MSGpOutTime
-
-		fmt.Printf("Avarage: ")
-		fmt.Printf("MSGp size:   %d bytes, Serialize: %d nanosec, Deserialize: %d nanosec\n Total time: %d\n\n", MSGpSize, MSGpInTime, MSGpOutTime, MSGpInTime+MSGpOutTime)
-
 	}
-
-	fmt.Printf("Overall Gob\n Sum: size: %d serializationTime: %d deserializationTime: %d \nSumTime: %d\n", Gob["size"], Gob["inTime"], Gob["outTime"], Gob["inTime"]+Gob["outTime"])
-
-	fmt.Printf("Overall XML\n Sum: size: %d serializationTime: %d deserializationTime: %d \nSumTime: %d\n", XML["size"], XML["inTime"], XML["outTime"], XML["inTime"]+XML["outTime"])
-
-	fmt.Printf("Overall JSON\n Sum: size: %d serializationTime: %d deserializationTime: %d \nSumTime: %d\n", JSON["size"], JSON["inTime"], JSON["outTime"], JSON["inTime"]+JSON["outTime"])
-
-	fmt.Printf("Overall Proto\n Sum: size: %d serializationTime: %d deserializationTime: %d \nSumTime: %d\n", Proto["size"], Proto["inTime"], Proto["outTime"], Proto["inTime"]+Proto["outTime"])
-
-	fmt.Printf("Overall Avro\n Sum: size: %d serializationTime: %d deserializationTime: %d \nSumTime: %d\n", Avro["size"], Avro["inTime"], Avro["outTime"], Avro["inTime"]+Avro["outTime"])
-
-	fmt.Printf("Overall YAML\n Sum: size: %d serializationTime: %d deserializationTime: %d \nSumTime: %d\n", YAML["size"], YAML["inTime"], YAML["outTime"], YAML["inTime"]+YAML["outTime"])
-
-	fmt.Printf("Overall MSG\n Sum: size: %d serializationTime: %d deserializationTime: %d \nSumTime: %d\n", MSG["size"], MSG["inTime"], MSG["outTime"], MSG["inTime"]+MSG["outTime"])
-
 }
@@ -0,0 +1,369 @@
+// Code generated by easyjson for marshaling/unmarshaling. DO NOT EDIT.
+
+package main
+
+import (
+	json "encoding/json"
+	easyjson "github.com/mailru/easyjson"
+	jlexer "github.com/mailru/easyjson/jlexer"
+	jwriter "github.com/mailru/easyjson/jwriter"
+)
+
+// suppress unused package warning
+var (
+	_ *json.RawMessage
+	_ *jlexer.Lexer
+	_ *jwriter.Writer
+	_ easyjson.Marshaler
+)
+
+func easyjson89aae3efDecodeMain(in *jlexer.Lexer, out *Test) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		switch key {
+		case "id":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.ID = int32(in.Int32())
+			}
+		case "name":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.Name = string(in.String())
+			}
+		case "somenumericarray":
+			if in.IsNull() {
+				in.Skip()
+				out.SomeNumericArray = nil
+			} else {
+				in.Delim('[')
+				if out.SomeNumericArray == nil {
+					if !in.IsDelim(']') {
+						out.SomeNumericArray = make([]int32, 0, 16)
+					} else {
+						out.SomeNumericArray = []int32{}
+					}
+				} else {
+					out.SomeNumericArray = (out.SomeNumericArray)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v1 int32
+					if in.IsNull() {
+						in.Skip()
+					} else {
+						v1 = int32(in.Int32())
+					}
+					out.SomeNumericArray = append(out.SomeNumericArray, v1)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		case "somefloatarray":
+			if in.IsNull() {
+				in.Skip()
+				out.SomeFloatArray = nil
+			} else {
+				in.Delim('[')
+				if out.SomeFloatArray == nil {
+					if !in.IsDelim(']') {
+						out.SomeFloatArray = make([]float32, 0, 16)
+					} else {
+						out.SomeFloatArray = []float32{}
+					}
+				} else {
+					out.SomeFloatArray = (out.SomeFloatArray)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v2 float32
+					if in.IsNull() {
+						in.Skip()
+					} else {
+						v2 = float32(in.Float32())
+					}
+					out.SomeFloatArray = append(out.SomeFloatArray, v2)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		case "tests":
+			if in.IsNull() {
+				in.Skip()
+				out.Tests = nil
+			} else {
+				in.Delim('[')
+				if out.Tests == nil {
+					if !in.IsDelim(']') {
+						out.Tests = make([]TestStruct, 0, 1)
+					} else {
+						out.Tests = []TestStruct{}
+					}
+				} else {
+					out.Tests = (out.Tests)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v3 TestStruct
+					easyjson89aae3efDecodeMain1(in, &v3)
+					out.Tests = append(out.Tests, v3)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		case "comment":
+			if in.IsNull() {
+				in.Skip()
+				out.Comment = nil
+			} else {
+				if out.Comment == nil {
+					out.Comment = new(string)
+				}
+				if in.IsNull() {
+					in.Skip()
+				} else {
+					*out.Comment = string(in.String())
+				}
+			}
+		case "labels":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				in.Delim('{')
+				if !in.IsDelim('}') {
+					out.Labels = make(Labels)
+				} else {
+					out.Labels = nil
+				}
+				for !in.IsDelim('}') {
+					key := string(in.String())
+					in.WantColon()
+					var v4 string
+					if in.IsNull() {
+						in.Skip()
+					} else {
+						v4 = string(in.String())
+					}
+					(out.Labels)[key] = v4
+					in.WantComma()
+				}
+				in.Delim('}')
+			}
+		case "blob":
+			if in.IsNull() {
+				in.Skip()
+				out.Blob = nil
+			} else {
+				out.Blob = in.Bytes()
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson89aae3efEncodeMain(out *jwriter.Writer, in Test) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"id\":"
+		out.RawString(prefix[1:])
+		out.Int32(int32(in.ID))
+	}
+	{
+		const prefix string = ",\"name\":"
+		out.RawString(prefix)
+		out.String(string(in.Name))
+	}
+	{
+		const prefix string = ",\"somenumericarray\":"
+		out.RawString(prefix)
+		if in.SomeNumericArray == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v6, v7 := range in.SomeNumericArray {
+				if v6 > 0 {
+					out.RawByte(',')
+				}
+				out.Int32(int32(v7))
+			}
+			out.RawByte(']')
+		}
+	}
+	{
+		const prefix string = ",\"somefloatarray\":"
+		out.RawString(prefix)
+		if in.SomeFloatArray == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v8, v9 := range in.SomeFloatArray {
+				if v8 > 0 {
+					out.RawByte(',')
+				}
+				out.Float32(float32(v9))
+			}
+			out.RawByte(']')
+		}
+	}
+	{
+		const prefix string = ",\"tests\":"
+		out.RawString(prefix)
+		if in.Tests == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v10, v11 := range in.Tests {
+				if v10 > 0 {
+					out.RawByte(',')
+				}
+				easyjson89aae3efEncodeMain1(out, v11)
+			}
+			out.RawByte(']')
+		}
+	}
+	if in.Comment != nil {
+		const prefix string = ",\"comment\":"
+		out.RawString(prefix)
+		out.String(string(*in.Comment))
+	}
+	if len(in.Labels) != 0 {
+		const prefix string = ",\"labels\":"
+		out.RawString(prefix)
+		{
+			out.RawByte('{')
+			v12First := true
+			for v12Name, v12Value := range in.Labels {
+				if v12First {
+					v12First = false
+				} else {
+					out.RawByte(',')
+				}
+				out.String(string(v12Name))
+				out.RawByte(':')
+				out.String(string(v12Value))
+			}
+			out.RawByte('}')
+		}
+	}
+	if len(in.Blob) != 0 {
+		const prefix string = ",\"blob\":"
+		out.RawString(prefix)
+		out.Base64Bytes(in.Blob)
+	}
+	out.RawByte('}')
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v Test) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson89aae3efEncodeMain(w, v)
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *Test) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson89aae3efDecodeMain(l, v)
+}
+func easyjson89aae3efDecodeMain1(in *jlexer.Lexer, out *TestStruct) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		switch key {
+		case "some":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.Some = string(in.String())
+			}
+		case "other":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.Other = string(in.String())
+			}
+		case "children":
+			if in.IsNull() {
+				in.Skip()
+				out.Children = nil
+			} else {
+				in.Delim('[')
+				if out.Children == nil {
+					if !in.IsDelim(']') {
+						out.Children = make([]TestStruct, 0, 1)
+					} else {
+						out.Children = []TestStruct{}
+					}
+				} else {
+					out.Children = (out.Children)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v15 TestStruct
+					easyjson89aae3efDecodeMain1(in, &v15)
+					out.Children = append(out.Children, v15)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson89aae3efEncodeMain1(out *jwriter.Writer, in TestStruct) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"some\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.Some))
+	}
+	{
+		const prefix string = ",\"other\":"
+		out.RawString(prefix)
+		out.String(string(in.Other))
+	}
+	if len(in.Children) != 0 {
+		const prefix string = ",\"children\":"
+		out.RawString(prefix)
+		{
+			out.RawByte('[')
+			for v16, v17 := range in.Children {
+				if v16 > 0 {
+					out.RawByte(',')
+				}
+				easyjson89aae3efEncodeMain1(out, v17)
+			}
+			out.RawByte(']')
+		}
+	}
+	out.RawByte('}')
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/metrics"
+	"time"
+)
+
+// memUsage is what a single encode or decode cost the allocator.
+type memUsage struct {
+	allocs uint64
+	bytes  uint64
+}
+
+// memDelta computes the allocations made between two snapshots. Only
+// these deltas belong to the call; the heap size is process wide.
+func memDelta(before, after *runtime.MemStats) memUsage {
+	return memUsage{
+		allocs: after.Mallocs - before.Mallocs,
+		bytes:  after.TotalAlloc - before.TotalAlloc,
+	}
+}
+
+// MemStats summarises memUsage samples of one codec operation. PeakHeap is
+// the heap high-water mark of one such operation run on its own, see
+// peakHeap.
+type MemStats struct {
+	AllocsPerOp float64 `json:"allocs_per_op"`
+	BytesPerOp  float64 `json:"bytes_per_op"`
+	PeakHeap    uint64  `json:"peak_heap_bytes"`
+}
+
+func summariseMem(samples []memUsage, peak uint64) MemStats {
+	s := MemStats{PeakHeap: peak}
+	if len(samples) == 0 {
+		return s
+	}
+	var allocs, bytes uint64
+	for _, m := range samples {
+		allocs += m.allocs
+		bytes += m.bytes
+	}
+	s.AllocsPerOp = float64(allocs) / float64(len(samples))
+	s.BytesPerOp = float64(bytes) / float64(len(samples))
+	return s
+}
+
+func (s MemStats) String() string {
+	return fmt.Sprintf("%.1f allocs/op %.0f B/op peak heap %d B", s.AllocsPerOp, s.BytesPerOp, s.PeakHeap)
+}
+
+// heapObjects is the runtime/metrics name of the bytes held by heap
+// objects. Reading it does not stop the world, but it only moves when a
+// whole span is handed out, so it is too coarse for a single call.
+const heapObjects = "/memory/classes/heap/objects:bytes"
+
+// heapSampler finds how far the heap grows above its collected size while
+// something runs: a goroutine keeps sampling the heap until stop, so
+// memory that is freed again before the end is still counted. It is a
+// sampler: with a single processor it only gets to run when the measured
+// code blocks, yields or is preempted, and a peak between two samples is
+// missed.
+type heapSampler struct {
+	exact      bool
+	every      time.Duration
+	base, peak uint64
+	// one set for the sampling goroutine and one for start and stop
+	bgStats, ownStats runtime.MemStats
+	bg, own           []metrics.Sample
+	stopped, done     chan struct{}
+}
+
+// startHeapSampler collects the heap and starts sampling, sleeping every
+// between samples. exact reads HeapAlloc through ReadMemStats, which is
+// precise to the byte but stops the world on every sample; otherwise the
+// cheaper heapObjects metric is read.
+func startHeapSampler(exact bool, every time.Duration) *heapSampler {
+	// allocated before the collection, so the sampler itself is in base
+	h := &heapSampler{
+		exact:   exact,
+		every:   every,
+		bg:      []metrics.Sample{{Name: heapObjects}},
+		own:     []metrics.Sample{{Name: heapObjects}},
+		stopped: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	started := make(chan struct{})
+	go h.run(started)
+	runtime.GC()
+	h.base = h.read(&h.ownStats, h.own)
+	h.peak = h.base
+	close(started)
+	return h
+}
+
+func (h *heapSampler) run(started <-chan struct{}) {
+	defer close(h.done)
+	<-started
+	for {
+		if v := h.read(&h.bgStats, h.bg); v > h.peak {
+			h.peak = v
+		}
+		select {
+		case <-h.stopped:
+			return
+		default:
+		}
+		if h.every > 0 {
+			time.Sleep(h.every)
+		} else {
+			// leave the processor to the measured call, there may be one only
+			runtime.Gosched()
+		}
+	}
+}
+
+// stop ends sampling and returns the high-water mark above the base.
+func (h *heapSampler) stop() uint64 {
+	close(h.stopped)
+	<-h.done
+	if v := h.read(&h.ownStats, h.own); v > h.peak {
+		h.peak = v
+	}
+	return h.peak - h.base
+}
+
+func (h *heapSampler) read(m *runtime.MemStats, sample []metrics.Sample) uint64 {
+	if h.exact {
+		runtime.ReadMemStats(m)
+		return m.HeapAlloc
+	}
+	metrics.Read(sample)
+	return sample[0].Value.Uint64()
+}
+
+// measurePeakHeap turns the extra peakHeap calls on, from -peak-heap.
+// Each one starts with a full collection, whose cost grows with the live
+// heap, so they get slow with many tests; off, PeakHeap stays 0.
+var measurePeakHeap = true
+
+// peakHeap runs op once after a collection, with nothing else measured at
+// the time, and returns its heap high-water mark. The exact sampler would
+// distort timings, so this is kept out of the timed runs. Unless a
+// collection happens inside op, nothing it allocated is freed yet and the
+// mark equals its bytes allocated.
+func peakHeap(op func()) uint64 {
+	h := startHeapSampler(true, 0)
+	op()
+	return h.stop()
+}
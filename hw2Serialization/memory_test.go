@@ -0,0 +1,32 @@
+package main
+
+import (
+	"runtime"
+	"testing"
+)
+
+var peakSink []byte
+
+// TestPeakHeap checks that the high-water mark counts what a call held,
+// also when it dropped the memory and a collection ran before it returned.
+// The call yields once while holding it, so the sampler gets a turn even
+// with GOMAXPROCS=1.
+func TestPeakHeap(t *testing.T) {
+	// the rest of the process may free a little while the call runs
+	const size, least = 8 << 20, 7 << 20
+	kept := peakHeap(func() { peakSink = make([]byte, size) })
+	peakSink = nil
+	if kept < least {
+		t.Errorf("kept %d B, peak %d B", size, kept)
+	}
+
+	dropped := peakHeap(func() {
+		peakSink = make([]byte, size)
+		runtime.Gosched()
+		peakSink = nil
+		runtime.GC()
+	})
+	if dropped < least {
+		t.Errorf("dropped %d B before returning, peak %d B", size, dropped)
+	}
+}
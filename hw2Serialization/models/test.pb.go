@@ -1,7 +1,7 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.25.0-devel
-// 	protoc        v3.14.0
+// 	protoc-gen-go v1.36.9
+// 	protoc        (unknown)
 // source: test.proto
 
 package __
@@ -11,6 +11,7 @@ import (
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
 	reflect "reflect"
 	sync "sync"
+	unsafe "unsafe"
 )
 
 const (
@@ -21,21 +22,19 @@ const (
 )
 
 type TestStruct struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Some          string                 `protobuf:"bytes,1,opt,name=Some,proto3" json:"Some,omitempty"`
+	Other         string                 `protobuf:"bytes,2,opt,name=Other,proto3" json:"Other,omitempty"`
+	Children      []*TestStruct          `protobuf:"bytes,3,rep,name=children,proto3" json:"children,omitempty"`
 	unknownFields protoimpl.UnknownFields
-
-	Some  string `protobuf:"bytes,1,opt,name=Some,proto3" json:"Some,omitempty"`
-	Other string `protobuf:"bytes,2,opt,name=Other,proto3" json:"Other,omitempty"`
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *TestStruct) Reset() {
 	*x = TestStruct{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_test_proto_msgTypes[0]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
+	mi := &file_test_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
 func (x *TestStruct) String() string {
@@ -46,7 +45,7 @@ func (*TestStruct) ProtoMessage() {}
 
 func (x *TestStruct) ProtoReflect() protoreflect.Message {
 	mi := &file_test_proto_msgTypes[0]
-	if protoimpl.UnsafeEnabled && x != nil {
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -75,25 +74,32 @@ func (x *TestStruct) GetOther() string {
 	return ""
 }
 
-type Test struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
-	unknownFields protoimpl.UnknownFields
+func (x *TestStruct) GetChildren() []*TestStruct {
+	if x != nil {
+		return x.Children
+	}
+	return nil
+}
 
-	ID               int32         `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`
-	Name             string        `protobuf:"bytes,2,opt,name=Name,proto3" json:"Name,omitempty"`
-	SomeNumericArray []int32       `protobuf:"varint,3,rep,packed,name=SomeNumericArray,proto3" json:"SomeNumericArray,omitempty"`
-	SomeFloatArray   []float32     `protobuf:"fixed32,4,rep,packed,name=SomeFloatArray,proto3" json:"SomeFloatArray,omitempty"`
-	Tests            []*TestStruct `protobuf:"bytes,5,rep,name=tests,proto3" json:"tests,omitempty"`
+type Test struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	ID               int32                  `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	Name             string                 `protobuf:"bytes,2,opt,name=Name,proto3" json:"Name,omitempty"`
+	SomeNumericArray []int32                `protobuf:"varint,3,rep,packed,name=SomeNumericArray,proto3" json:"SomeNumericArray,omitempty"`
+	SomeFloatArray   []float32              `protobuf:"fixed32,4,rep,packed,name=SomeFloatArray,proto3" json:"SomeFloatArray,omitempty"`
+	Tests            []*TestStruct          `protobuf:"bytes,5,rep,name=tests,proto3" json:"tests,omitempty"`
+	Comment          *string                `protobuf:"bytes,6,opt,name=Comment,proto3,oneof" json:"Comment,omitempty"`
+	Labels           map[string]string      `protobuf:"bytes,7,rep,name=Labels,proto3" json:"Labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Blob             []byte                 `protobuf:"bytes,8,opt,name=Blob,proto3" json:"Blob,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
 }
 
 func (x *Test) Reset() {
 	*x = Test{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_test_proto_msgTypes[1]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
+	mi := &file_test_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
 func (x *Test) String() string {
@@ -104,7 +110,7 @@ func (*Test) ProtoMessage() {}
 
 func (x *Test) ProtoReflect() protoreflect.Message {
 	mi := &file_test_proto_msgTypes[1]
-	if protoimpl.UnsafeEnabled && x != nil {
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -154,51 +160,80 @@ func (x *Test) GetTests() []*TestStruct {
 	return nil
 }
 
-var File_test_proto protoreflect.FileDescriptor
+func (x *Test) GetComment() string {
+	if x != nil && x.Comment != nil {
+		return *x.Comment
+	}
+	return ""
+}
+
+func (x *Test) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
 
-var file_test_proto_rawDesc = []byte{
-	0x0a, 0x0a, 0x74, 0x65, 0x73, 0x74, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x36, 0x0a, 0x0a,
-	0x54, 0x65, 0x73, 0x74, 0x53, 0x74, 0x72, 0x75, 0x63, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x53, 0x6f,
-	0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x53, 0x6f, 0x6d, 0x65, 0x12, 0x14,
-	0x0a, 0x05, 0x4f, 0x74, 0x68, 0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x4f,
-	0x74, 0x68, 0x65, 0x72, 0x22, 0xa1, 0x01, 0x0a, 0x04, 0x54, 0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a,
-	0x02, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x02, 0x49, 0x44, 0x12, 0x12, 0x0a,
-	0x04, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x4e, 0x61, 0x6d,
-	0x65, 0x12, 0x2a, 0x0a, 0x10, 0x53, 0x6f, 0x6d, 0x65, 0x4e, 0x75, 0x6d, 0x65, 0x72, 0x69, 0x63,
-	0x41, 0x72, 0x72, 0x61, 0x79, 0x18, 0x03, 0x20, 0x03, 0x28, 0x05, 0x52, 0x10, 0x53, 0x6f, 0x6d,
-	0x65, 0x4e, 0x75, 0x6d, 0x65, 0x72, 0x69, 0x63, 0x41, 0x72, 0x72, 0x61, 0x79, 0x12, 0x26, 0x0a,
-	0x0e, 0x53, 0x6f, 0x6d, 0x65, 0x46, 0x6c, 0x6f, 0x61, 0x74, 0x41, 0x72, 0x72, 0x61, 0x79, 0x18,
-	0x04, 0x20, 0x03, 0x28, 0x02, 0x52, 0x0e, 0x53, 0x6f, 0x6d, 0x65, 0x46, 0x6c, 0x6f, 0x61, 0x74,
-	0x41, 0x72, 0x72, 0x61, 0x79, 0x12, 0x21, 0x0a, 0x05, 0x74, 0x65, 0x73, 0x74, 0x73, 0x18, 0x05,
-	0x20, 0x03, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x54, 0x65, 0x73, 0x74, 0x53, 0x74, 0x72, 0x75, 0x63,
-	0x74, 0x52, 0x05, 0x74, 0x65, 0x73, 0x74, 0x73, 0x42, 0x03, 0x5a, 0x01, 0x2e, 0x62, 0x06, 0x70,
-	0x72, 0x6f, 0x74, 0x6f, 0x33,
+func (x *Test) GetBlob() []byte {
+	if x != nil {
+		return x.Blob
+	}
+	return nil
 }
 
+var File_test_proto protoreflect.FileDescriptor
+
+const file_test_proto_rawDesc = "" +
+	"\n" +
+	"\n" +
+	"test.proto\"_\n" +
+	"\n" +
+	"TestStruct\x12\x12\n" +
+	"\x04Some\x18\x01 \x01(\tR\x04Some\x12\x14\n" +
+	"\x05Other\x18\x02 \x01(\tR\x05Other\x12'\n" +
+	"\bchildren\x18\x03 \x03(\v2\v.TestStructR\bchildren\"\xc6\x02\n" +
+	"\x04Test\x12\x0e\n" +
+	"\x02ID\x18\x01 \x01(\x05R\x02ID\x12\x12\n" +
+	"\x04Name\x18\x02 \x01(\tR\x04Name\x12*\n" +
+	"\x10SomeNumericArray\x18\x03 \x03(\x05R\x10SomeNumericArray\x12&\n" +
+	"\x0eSomeFloatArray\x18\x04 \x03(\x02R\x0eSomeFloatArray\x12!\n" +
+	"\x05tests\x18\x05 \x03(\v2\v.TestStructR\x05tests\x12\x1d\n" +
+	"\aComment\x18\x06 \x01(\tH\x00R\aComment\x88\x01\x01\x12)\n" +
+	"\x06Labels\x18\a \x03(\v2\x11.Test.LabelsEntryR\x06Labels\x12\x12\n" +
+	"\x04Blob\x18\b \x01(\fR\x04Blob\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01B\n" +
+	"\n" +
+	"\b_CommentB\x03Z\x01.b\x06proto3"
+
 var (
 	file_test_proto_rawDescOnce sync.Once
-	file_test_proto_rawDescData = file_test_proto_rawDesc
+	file_test_proto_rawDescData []byte
 )
 
 func file_test_proto_rawDescGZIP() []byte {
 	file_test_proto_rawDescOnce.Do(func() {
-		file_test_proto_rawDescData = protoimpl.X.CompressGZIP(file_test_proto_rawDescData)
+		file_test_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_test_proto_rawDesc), len(file_test_proto_rawDesc)))
 	})
 	return file_test_proto_rawDescData
 }
 
-var file_test_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
-var file_test_proto_goTypes = []interface{}{
+var file_test_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_test_proto_goTypes = []any{
 	(*TestStruct)(nil), // 0: TestStruct
 	(*Test)(nil),       // 1: Test
+	nil,                // 2: Test.LabelsEntry
 }
 var file_test_proto_depIdxs = []int32{
-	0, // 0: Test.tests:type_name -> TestStruct
-	1, // [1:1] is the sub-list for method output_type
-	1, // [1:1] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	0, // 0: TestStruct.children:type_name -> TestStruct
+	0, // 1: Test.tests:type_name -> TestStruct
+	2, // 2: Test.Labels:type_name -> Test.LabelsEntry
+	3, // [3:3] is the sub-list for method output_type
+	3, // [3:3] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
 }
 
 func init() { file_test_proto_init() }
@@ -206,39 +241,14 @@ func file_test_proto_init() {
 	if File_test_proto != nil {
 		return
 	}
-	if !protoimpl.UnsafeEnabled {
-		file_test_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*TestStruct); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_test_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Test); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-	}
+	file_test_proto_msgTypes[1].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
-			RawDescriptor: file_test_proto_rawDesc,
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_test_proto_rawDesc), len(file_test_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   2,
+			NumMessages:   3,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -247,7 +257,6 @@ func file_test_proto_init() {
 		MessageInfos:      file_test_proto_msgTypes,
 	}.Build()
 	File_test_proto = out.File
-	file_test_proto_rawDesc = nil
 	file_test_proto_goTypes = nil
 	file_test_proto_depIdxs = nil
 }
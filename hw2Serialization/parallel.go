@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ParallelResult is the round-trip throughput of one codec with a given
+// number of workers, each on its own P (GOMAXPROCS is set to Workers).
+// Speedup is relative to one worker; Efficiency is Speedup per worker,
+// so codecs contending on shared state fall well below 1.
+type ParallelResult struct {
+	Codec      string  `json:"codec"`
+	Workers    int     `json:"workers"`
+	Ops        int64   `json:"ops"`
+	OpsPerSec  float64 `json:"ops_per_sec"`
+	MBPerSec   float64 `json:"mb_per_sec"`
+	Speedup    float64 `json:"speedup"`
+	Efficiency float64 `json:"efficiency"`
+	Errors     int64   `json:"errors"`
+}
+
+// workerCounts returns 1, 2, 4, ... up to and including max.
+func workerCounts(max int) []int {
+	var counts []int
+	for n := 1; n < max; n *= 2 {
+		counts = append(counts, n)
+	}
+	return append(counts, max)
+}
+
+// runParallel lets workers goroutines marshal and unmarshal values with c
+// in a loop for d and counts the completed round trips.
+func runParallel(c Codec, values []interface{}, workers int, d time.Duration) ParallelResult {
+	prev := runtime.GOMAXPROCS(workers)
+	defer runtime.GOMAXPROCS(prev)
+
+	var ops, bytes, errs int64
+	var stop int32
+	var wg sync.WaitGroup
+
+	start := time.Now()
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			var n, b, e int64
+			for i := w; atomic.LoadInt32(&stop) == 0; i++ {
+				data, err := c.Marshal(values[i%len(values)])
+				if err == nil {
+					err = c.Unmarshal(data, newModel(c))
+				}
+				if err != nil {
+					e++
+				}
+				n++
+				b += int64(len(data))
+			}
+			atomic.AddInt64(&ops, n)
+			atomic.AddInt64(&bytes, b)
+			atomic.AddInt64(&errs, e)
+		}(w)
+	}
+	time.Sleep(d)
+	atomic.StoreInt32(&stop, 1)
+	wg.Wait()
+	elapsed := time.Since(start).Seconds()
+
+	return ParallelResult{
+		Codec:     c.Name(),
+		Workers:   workers,
+		Ops:       ops,
+		OpsPerSec: float64(ops) / elapsed,
+		MBPerSec:  float64(bytes) / 1e6 / elapsed,
+		Errors:    errs,
+	}
+}
+
+// runParallels measures every enabled codec on the corpus with 1 to max
+// workers and returns the process exit code.
+func runParallels(enabled []Codec, tests []Test, max, warmup int, d time.Duration, report *Report) int {
+	if max > runtime.NumCPU() {
+		fmt.Printf("note: %d workers on %d CPUs, scaling past %d is not meaningful\n", max, runtime.NumCPU(), runtime.NumCPU())
+	}
+
+	code := 0
+	for _, c := range enabled {
+		values := make([]interface{}, len(tests))
+		for i, t := range tests {
+			values[i] = modelOf(c, t)
+			for j := 0; j < warmup; j++ {
+				if data, err := c.Marshal(values[i]); err == nil {
+					c.Unmarshal(data, newModel(c))
+				}
+			}
+		}
+
+		fmt.Printf("%s\n  %7s %12s %10s %8s %10s\n", c.Name(), "workers", "ops/s", "MB/s", "speedup", "efficiency")
+		var base float64
+		for _, n := range workerCounts(max) {
+			r := runParallel(c, values, n, d)
+			if n == 1 {
+				base = r.OpsPerSec
+			}
+			if base > 0 {
+				r.Speedup = r.OpsPerSec / base
+				r.Efficiency = r.Speedup / float64(n)
+			}
+			report.Parallel = append(report.Parallel, r)
+
+			fmt.Printf("  %7d %12.0f %10.2f %8.2f %10.2f\n", r.Workers, r.OpsPerSec, r.MBPerSec, r.Speedup, r.Efficiency)
+			if r.Errors > 0 {
+				fmt.Printf("  %d of %d round trips failed\n", r.Errors, r.Ops)
+				code = 1
+			}
+		}
+		fmt.Println()
+	}
+	return code
+}
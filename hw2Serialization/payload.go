@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"unicode"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Shape describes what generated Test values look like. It is loaded
+// from a YAML or JSON file with -payload; see payloads/ for examples.
+type Shape struct {
+	Numbers  Range      `yaml:"numbers" json:"numbers"`
+	Floats   Range      `yaml:"floats" json:"floats"`
+	Tests    Range      `yaml:"tests" json:"tests"`
+	Strings  StringDist `yaml:"strings" json:"strings"`
+	Depth    int        `yaml:"depth" json:"depth"`
+	Children Range      `yaml:"children" json:"children"`
+	Optional float64    `yaml:"optional" json:"optional"`
+	Labels   Range      `yaml:"labels" json:"labels"`
+	Blob     Range      `yaml:"blob" json:"blob"`
+}
+
+// Range is an inclusive length range, picked uniformly.
+type Range struct {
+	Min int `yaml:"min" json:"min"`
+	Max int `yaml:"max" json:"max"`
+}
+
+// StringDist is the length distribution of every generated string:
+// "uniform" over [Min, Max], "normal" around Mean with StdDev or "exp"
+// with the given Mean, the last two clipped to [Min, Max].
+type StringDist struct {
+	Dist   string  `yaml:"dist" json:"dist"`
+	Min    int     `yaml:"min" json:"min"`
+	Max    int     `yaml:"max" json:"max"`
+	Mean   float64 `yaml:"mean" json:"mean"`
+	StdDev float64 `yaml:"stddev" json:"stddev"`
+}
+
+// Built-in shapes, matching what the benchmark generated originally.
+var (
+	fullShape = Shape{
+		Numbers: Range{0, 255},
+		Floats:  Range{0, 255},
+		Tests:   Range{0, 255},
+		Strings: StringDist{Dist: "uniform", Min: 0, Max: 31},
+	}
+	simpleShape = Shape{
+		Numbers: Range{0, 2},
+		Floats:  Range{0, 2},
+		Tests:   Range{0, 2},
+		Strings: StringDist{Dist: "uniform", Min: 0, Max: 31},
+	}
+)
+
+// loadShape returns a built-in shape ("full" or "simple") or reads one from a file.
+func loadShape(name string) (Shape, error) {
+	switch name {
+	case "", "full":
+		return fullShape, nil
+	case "simple":
+		return simpleShape, nil
+	}
+
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return Shape{}, err
+	}
+	shape := fullShape
+	if err := yaml.UnmarshalStrict(data, &shape); err != nil {
+		return Shape{}, fmt.Errorf("%s: %v", name, err)
+	}
+	return shape, shape.validate()
+}
+
+func (s Shape) validate() error {
+	ranges := map[string]Range{
+		"numbers": s.Numbers, "floats": s.Floats, "tests": s.Tests,
+		"children": s.Children, "labels": s.Labels, "blob": s.Blob,
+	}
+	for name, r := range ranges {
+		if r.Min < 0 || r.Max < r.Min {
+			return fmt.Errorf("%s: invalid range [%d, %d]", name, r.Min, r.Max)
+		}
+	}
+	if s.Strings.Min < 0 || s.Strings.Max < s.Strings.Min {
+		return fmt.Errorf("strings: invalid range [%d, %d]", s.Strings.Min, s.Strings.Max)
+	}
+	switch s.Strings.Dist {
+	case "", "uniform", "normal", "exp":
+	default:
+		return fmt.Errorf("strings: unknown distribution %q (uniform, normal, exp)", s.Strings.Dist)
+	}
+	if s.Depth < 0 {
+		return fmt.Errorf("depth: must not be negative")
+	}
+	if s.Optional < 0 || s.Optional > 1 {
+		return fmt.Errorf("optional: probability must be in [0, 1]")
+	}
+	return nil
+}
+
+func (r Range) pick(rnd *rand.Rand) int {
+	return r.Min + rnd.Intn(r.Max-r.Min+1)
+}
+
+func (d StringDist) pick(rnd *rand.Rand) int {
+	var n float64
+	switch d.Dist {
+	case "normal":
+		n = d.Mean + rnd.NormFloat64()*d.StdDev
+	case "exp":
+		n = float64(d.Min) + rnd.ExpFloat64()*d.Mean
+	default:
+		return d.Min + rnd.Intn(d.Max-d.Min+1)
+	}
+	return int(math.Max(float64(d.Min), math.Min(float64(d.Max), math.Round(n))))
+}
+
+func randString(rnd *rand.Rand, dist StringDist) string {
+	var letters = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789абвгдеёжзийклмнопрстуфхцчшщъыьэюяАБВГДЕЁЖЗИЙКЛМНОПРСТУФХЦЧШЩЪЫЬЭЮЯ")
+	s := make([]rune, dist.pick(rnd))
+	for i := range s {
+		s[i] = letters[rnd.Intn(len(letters))]
+		for i == 0 && unicode.IsDigit(s[i]) {
+			s[i] = letters[rnd.Intn(len(letters))]
+		}
+	}
+	return string(s)
+}
+
+// generateTest draws one Test of the given shape from rnd; the same
+// seed and shape always give the same value.
+func generateTest(rnd *rand.Rand, shape Shape) Test {
+	arrInt := make([]int32, shape.Numbers.pick(rnd))
+	arrTestStruct := make([]TestStruct, shape.Tests.pick(rnd))
+	arrFloat := make([]float32, shape.Floats.pick(rnd))
+
+	for i := range arrInt {
+		arrInt[i] = rnd.Int31()
+	}
+
+	for i := range arrTestStruct {
+		arrTestStruct[i] = generateTestStruct(rnd, shape, shape.Depth)
+	}
+	for i := range arrFloat {
+		arrFloat[i] = rnd.Float32()
+	}
+
+	t := Test{
+		ID:               rnd.Int31(),
+		Name:             randString(rnd, shape.Strings),
+		Tests:            arrTestStruct,
+		SomeFloatArray:   arrFloat,
+		SomeNumericArray: arrInt,
+	}
+
+	if shape.Optional > 0 && rnd.Float64() < shape.Optional {
+		comment := randString(rnd, shape.Strings)
+		t.Comment = &comment
+	}
+	if n := shape.Labels.pick(rnd); n > 0 {
+		t.Labels = make(Labels, n)
+		for i := 0; i < n; i++ {
+			t.Labels[fmt.Sprintf("label%d", i)] = randString(rnd, shape.Strings)
+		}
+	}
+	if n := shape.Blob.pick(rnd); n > 0 {
+		t.Blob = make([]byte, n)
+		rnd.Read(t.Blob)
+	}
+	return t
+}
+
+// generateTestStruct nests depth more levels of children below the returned struct.
+func generateTestStruct(rnd *rand.Rand, shape Shape, depth int) TestStruct {
+	ts := TestStruct{Some: randString(rnd, shape.Strings), Other: randString(rnd, shape.Strings)}
+	if depth > 0 {
+		if n := shape.Children.pick(rnd); n > 0 {
+			ts.Children = make([]TestStruct, n)
+			for i := range ts.Children {
+				ts.Children[i] = generateTestStruct(rnd, shape, depth-1)
+			}
+		}
+	}
+	return ts
+}
@@ -0,0 +1,544 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Report is the machine-readable outcome of one benchmark run.
+type Report struct {
+	Timestamp  time.Time          `json:"timestamp"`
+	GoVersion  string             `json:"go_version"`
+	Commit     string             `json:"commit,omitempty"`
+	Machine    MachineInfo        `json:"machine"`
+	Config     ReportConfig       `json:"config"`
+	Results    []Result           `json:"results"`
+	Overall    []Result           `json:"overall"`
+	Streams    []StreamResult     `json:"streams,omitempty"`
+	Evolution  []EvolutionResult  `json:"evolution,omitempty"`
+	Parallel   []ParallelResult   `json:"parallel,omitempty"`
+	Robustness []RobustnessResult `json:"robustness,omitempty"`
+	Interop    []InteropResult    `json:"interop,omitempty"`
+	Sizes      []SizeBreakdown    `json:"sizes,omitempty"`
+	Edge       []EdgeResult       `json:"edge,omitempty"`
+}
+
+// ReportConfig records the flags a report was produced with.
+type ReportConfig struct {
+	Runs       int      `json:"runs"`
+	Warmup     int      `json:"warmup"`
+	Tests      int      `json:"tests"`
+	Payload    string   `json:"payload"`
+	Seed       int64    `json:"seed"`
+	Corpus     string   `json:"corpus,omitempty"`
+	Persist    bool     `json:"persist"`
+	Formats    []string `json:"formats"`
+	Compress   []string `json:"compress,omitempty"`
+	Stream     int      `json:"stream,omitempty"`
+	Evolution  bool     `json:"evolution,omitempty"`
+	Parallel   int      `json:"parallel,omitempty"`
+	Robustness int      `json:"robustness,omitempty"`
+	Interop    bool     `json:"interop,omitempty"`
+	Schema     string   `json:"schema,omitempty"`
+	Sizes      bool     `json:"sizes,omitempty"`
+	Edge       bool     `json:"edge,omitempty"`
+	Reuse      bool     `json:"reuse,omitempty"`
+	// ReuseEncodeOnly names the reuse variants whose decode is the naive one.
+	ReuseEncodeOnly []string `json:"reuse_encode_only,omitempty"`
+}
+
+// Result holds the measurements of one codec on one test case,
+// or over all test cases when Test is -1.
+type Result struct {
+	Test       int      `json:"test"`
+	Codec      string   `json:"codec"`
+	Size       int      `json:"size"`
+	Encode     Stats    `json:"encode"`
+	Decode     Stats    `json:"decode"`
+	Write      *Stats   `json:"write,omitempty"`
+	Read       *Stats   `json:"read,omitempty"`
+	EncodeMem  MemStats `json:"encode_mem"`
+	DecodeMem  MemStats `json:"decode_mem"`
+	FidelityOK bool     `json:"fidelity_ok"`
+
+	Compression []CompressionResult `json:"compression,omitempty"`
+}
+
+func newReport(cfg ReportConfig) *Report {
+	return &Report{
+		Timestamp: time.Now().UTC(),
+		GoVersion: runtime.Version(),
+		Commit:    gitCommit(),
+		Machine:   machineInfo(),
+		Config:    cfg,
+	}
+}
+
+// reportStdout is where a report without -out goes. main points os.Stdout
+// at stderr then, so it is kept here before that.
+var reportStdout io.Writer = os.Stdout
+
+func writeReport(r *Report, format, out string) error {
+	w := reportStdout
+	if out != "" {
+		f, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return renderReport(w, r, format)
+}
+
+// checkReportFormat fails for formats renderReport does not know, so
+// callers can reject them before running anything.
+func checkReportFormat(format string) error {
+	switch format {
+	case "json", "csv", "markdown", "md", "html":
+		return nil
+	}
+	return fmt.Errorf("unknown report format %q (json, csv, markdown, html)", format)
+}
+
+func renderReport(w io.Writer, r *Report, format string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case "csv":
+		return writeCSV(w, r)
+	case "markdown", "md":
+		return writeMarkdown(w, r)
+	case "html":
+		return writeHTML(w, r)
+	}
+	return checkReportFormat(format)
+}
+
+var csvHeader = []string{
+	"test", "codec", "size",
+	"encode_min_ns", "encode_median_ns", "encode_p95_ns", "encode_p99_ns", "encode_stddev_ns",
+	"decode_min_ns", "decode_median_ns", "decode_p95_ns", "decode_p99_ns", "decode_stddev_ns",
+	"encode_allocs", "encode_bytes", "encode_peak_heap",
+	"decode_allocs", "decode_bytes", "decode_peak_heap",
+	"fidelity_ok",
+}
+
+func writeCSV(w io.Writer, r *Report) error {
+	cw := csv.NewWriter(w)
+	if r.Config.Stream > 0 {
+		return writeStreamCSV(cw, r.Streams)
+	}
+	if r.Config.Evolution {
+		return writeEvolutionCSV(cw, r.Evolution)
+	}
+	if r.Config.Parallel > 0 {
+		return writeParallelCSV(cw, r.Parallel)
+	}
+	if r.Config.Robustness > 0 {
+		return writeRobustnessCSV(cw, r.Robustness)
+	}
+	if r.Config.Interop {
+		return writeInteropCSV(cw, r.Interop)
+	}
+	if r.Config.Sizes {
+		return writeSizesCSV(cw, r.Sizes)
+	}
+	if r.Config.Edge {
+		return writeEdgeCSV(cw, r.Edge)
+	}
+	header := append([]string{}, csvHeader...)
+	for _, name := range r.Config.Compress {
+		header = append(header, name+"_size", name+"_ratio", name+"_compress_median_ns", name+"_decompress_median_ns")
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	rows := append(append([]Result{}, r.Results...), r.Overall...)
+	for _, res := range rows {
+		test := strconv.Itoa(res.Test)
+		if res.Test < 0 {
+			test = "overall"
+		}
+		record := []string{test, res.Codec, strconv.Itoa(res.Size)}
+		record = append(record, statsFields(res.Encode)...)
+		record = append(record, statsFields(res.Decode)...)
+		record = append(record, memFields(res.EncodeMem)...)
+		record = append(record, memFields(res.DecodeMem)...)
+		record = append(record, strconv.FormatBool(res.FidelityOK))
+		for _, c := range res.Compression {
+			record = append(record, strconv.Itoa(c.Size), strconv.FormatFloat(c.Ratio, 'f', 3, 64),
+				strconv.FormatInt(int64(c.Compress.Median), 10), strconv.FormatInt(int64(c.Decompress.Median), 10))
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+var streamCSVHeader = []string{
+	"codec", "records", "bytes",
+	"encode_ns", "encode_records_per_sec", "encode_mb_per_sec", "encode_peak_heap",
+	"decode_ns", "decode_records_per_sec", "decode_mb_per_sec", "decode_peak_heap",
+	"failures",
+}
+
+func writeStreamCSV(cw *csv.Writer, streams []StreamResult) error {
+	if err := cw.Write(streamCSVHeader); err != nil {
+		return err
+	}
+	for _, res := range streams {
+		record := []string{res.Codec, strconv.Itoa(res.Records), strconv.FormatInt(res.Bytes, 10)}
+		record = append(record, streamPassFields(res.Encode)...)
+		record = append(record, streamPassFields(res.Decode)...)
+		record = append(record, strconv.Itoa(res.Failures))
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func streamPassFields(p StreamPass) []string {
+	return []string{
+		strconv.FormatInt(int64(p.Time), 10),
+		strconv.FormatFloat(p.RecordsPerSec, 'f', 0, 64),
+		strconv.FormatFloat(p.MBPerSec, 'f', 2, 64),
+		strconv.FormatUint(p.PeakHeap, 10),
+	}
+}
+
+func writeEvolutionCSV(cw *csv.Writer, results []EvolutionResult) error {
+	if err := cw.Write([]string{"codec", "record", "change", "backward", "forward", "backward_note", "forward_note"}); err != nil {
+		return err
+	}
+	for _, res := range results {
+		if err := cw.Write([]string{res.Codec, res.Record, res.Change, res.Backward, res.Forward, res.BackwardNote, res.ForwardNote}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeParallelCSV(cw *csv.Writer, results []ParallelResult) error {
+	if err := cw.Write([]string{"codec", "workers", "ops", "ops_per_sec", "mb_per_sec", "speedup", "efficiency", "errors"}); err != nil {
+		return err
+	}
+	for _, res := range results {
+		record := []string{
+			res.Codec, strconv.Itoa(res.Workers), strconv.FormatInt(res.Ops, 10),
+			strconv.FormatFloat(res.OpsPerSec, 'f', 0, 64),
+			strconv.FormatFloat(res.MBPerSec, 'f', 2, 64),
+			strconv.FormatFloat(res.Speedup, 'f', 2, 64),
+			strconv.FormatFloat(res.Efficiency, 'f', 2, 64),
+			strconv.FormatInt(res.Errors, 10),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeRobustnessCSV(cw *csv.Writer, results []RobustnessResult) error {
+	if err := cw.Write([]string{"codec", "mutation", "runs", "decoded", "errors", "panics", "hangs", "allocs", "crashes"}); err != nil {
+		return err
+	}
+	for _, res := range results {
+		record := []string{res.Codec, res.Mutation}
+		for _, n := range []int{res.Runs, res.Decoded, res.Errors, res.Panics, res.Hangs, res.Allocs, res.Crashes} {
+			record = append(record, strconv.Itoa(n))
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeInteropCSV(cw *csv.Writer, results []InteropResult) error {
+	if err := cw.Write([]string{"codec", "schema", "artifact", "canonical", "outcome", "golden", "note"}); err != nil {
+		return err
+	}
+	for _, res := range results {
+		if err := cw.Write([]string{res.Codec, res.Schema, res.Artifact, res.Canonical, res.Outcome, res.Golden, res.Note}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeSizesCSV(cw *csv.Writer, sizes []SizeBreakdown) error {
+	if err := cw.Write([]string{"codec", "part", "raw", "encoded", "share"}); err != nil {
+		return err
+	}
+	for _, b := range sizes {
+		rows := [][]string{{b.Codec, "total", "", strconv.Itoa(b.Total), "1"}}
+		for _, f := range b.Fields {
+			rows = append(rows, []string{b.Codec, f.Field, strconv.Itoa(f.Raw), strconv.Itoa(f.Encoded), sizeShare(f.Encoded, b.Total)})
+		}
+		rows = append(rows,
+			[]string{b.Codec, "empty", "", strconv.Itoa(b.Empty), sizeShare(b.Empty, b.Total)},
+			[]string{b.Codec, "other", "", strconv.Itoa(b.Other), sizeShare(b.Other, b.Total)})
+		if err := cw.WriteAll(rows); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeEdgeCSV(cw *csv.Writer, results []EdgeResult) error {
+	if err := cw.Write([]string{"codec", "case", "outcome", "detail"}); err != nil {
+		return err
+	}
+	for _, res := range results {
+		if err := cw.Write([]string{res.Codec, res.Case, res.Outcome, res.Detail}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func sizeShare(n, total int) string {
+	return strconv.FormatFloat(share(n, total)/100, 'f', 4, 64)
+}
+
+func statsFields(s Stats) []string {
+	return []string{
+		strconv.FormatInt(int64(s.Min), 10),
+		strconv.FormatInt(int64(s.Median), 10),
+		strconv.FormatInt(int64(s.P95), 10),
+		strconv.FormatInt(int64(s.P99), 10),
+		strconv.FormatInt(int64(s.StdDev), 10),
+	}
+}
+
+func memFields(m MemStats) []string {
+	return []string{
+		strconv.FormatFloat(m.AllocsPerOp, 'f', 1, 64),
+		strconv.FormatFloat(m.BytesPerOp, 'f', 1, 64),
+		strconv.FormatUint(m.PeakHeap, 10),
+	}
+}
+
+func writeMarkdown(w io.Writer, r *Report) error {
+	fmt.Fprintf(w, "## Serialization benchmark\n\n")
+	fmt.Fprintf(w, "%s, %s, %d tests x %d runs (warm-up %d)\n\n",
+		r.Timestamp.Format(time.RFC3339), r.GoVersion, r.Config.Tests, r.Config.Runs, r.Config.Warmup)
+
+	if r.Config.Evolution {
+		fmt.Fprintf(w, "### Schema evolution\n\n")
+		evolutionTable(w, r.Evolution)
+		return nil
+	}
+	if r.Config.Robustness > 0 {
+		fmt.Fprintf(w, "### Robustness, %d mutated inputs per mutation\n\n", r.Config.Robustness)
+		robustnessTable(w, r.Robustness)
+		return nil
+	}
+	if r.Config.Sizes {
+		fmt.Fprintf(w, "### Size breakdown\n\n")
+		sizesTable(w, r.Sizes)
+		return nil
+	}
+	if r.Config.Edge {
+		fmt.Fprintf(w, "### Edge values\n\n")
+		edgeTable(w, r.Edge)
+		return nil
+	}
+	if r.Config.Interop {
+		fmt.Fprintf(w, "### Interop\n\n")
+		interopTable(w, r.Interop)
+		return nil
+	}
+	if r.Config.Parallel > 0 {
+		fmt.Fprintf(w, "### Parallel round trips, up to %d workers\n\n", r.Config.Parallel)
+		parallelTable(w, r.Parallel)
+		return nil
+	}
+	if r.Config.Stream > 0 {
+		fmt.Fprintf(w, "### Streaming, %d records\n\n", r.Config.Stream)
+		streamTable(w, r.Streams)
+		return nil
+	}
+
+	fmt.Fprintf(w, "### Overall\n\n")
+	markdownTable(w, r.Overall)
+	if only := r.Config.ReuseEncodeOnly; len(only) > 0 {
+		fmt.Fprintf(w, "\n%s reuse state on encode only and decode like their naive codec.\n", strings.Join(only, ", "))
+	}
+	if len(r.Config.Compress) > 0 {
+		fmt.Fprintf(w, "\n### Compression\n\n")
+		compressionTable(w, r.Overall)
+	}
+
+	for test := 0; test < r.Config.Tests; test++ {
+		var rows []Result
+		for _, res := range r.Results {
+			if res.Test == test {
+				rows = append(rows, res)
+			}
+		}
+		fmt.Fprintf(w, "\n### Test #%d\n\n", test)
+		markdownTable(w, rows)
+	}
+	return nil
+}
+
+func markdownTable(w io.Writer, rows []Result) {
+	fmt.Fprintln(w, "| Codec | Size, bytes | Encode median | Encode p99 | Decode median | Decode p99 | Encode allocs | Encode B/op | Encode peak heap | Decode allocs | Decode B/op | Decode peak heap | Fidelity |")
+	fmt.Fprintln(w, "|---|---:|---:|---:|---:|---:|---:|---:|---:|---:|---:|---:|---|")
+	for _, res := range rows {
+		fidelity := "ok"
+		if !res.FidelityOK {
+			fidelity = "**failed**"
+		}
+		fmt.Fprintf(w, "| %s | %d | %v | %v | %v | %v | %.1f | %.0f | %d | %.1f | %.0f | %d | %s |\n",
+			res.Codec, res.Size, res.Encode.Median, res.Encode.P99, res.Decode.Median, res.Decode.P99,
+			res.EncodeMem.AllocsPerOp, res.EncodeMem.BytesPerOp, res.EncodeMem.PeakHeap,
+			res.DecodeMem.AllocsPerOp, res.DecodeMem.BytesPerOp, res.DecodeMem.PeakHeap, fidelity)
+	}
+}
+
+func compressionTable(w io.Writer, rows []Result) {
+	fmt.Fprintln(w, "| Codec | Compression | Raw size | Compressed size | Ratio | Compress median | Decompress median |")
+	fmt.Fprintln(w, "|---|---|---:|---:|---:|---:|---:|")
+	for _, res := range rows {
+		for _, c := range res.Compression {
+			fmt.Fprintf(w, "| %s | %s | %d | %d | %.2f | %v | %v |\n",
+				res.Codec, c.Algorithm, res.Size, c.Size, c.Ratio, c.Compress.Median, c.Decompress.Median)
+		}
+	}
+}
+
+func streamTable(w io.Writer, streams []StreamResult) {
+	fmt.Fprintln(w, "| Codec | Size, bytes | Encode records/s | Encode MB/s | Encode peak heap | Decode records/s | Decode MB/s | Decode peak heap | Fidelity |")
+	fmt.Fprintln(w, "|---|---:|---:|---:|---:|---:|---:|---:|---|")
+	for _, res := range streams {
+		fidelity := "ok"
+		if res.Failures > 0 {
+			fidelity = fmt.Sprintf("**failed on %d**", res.Failures)
+		}
+		fmt.Fprintf(w, "| %s | %d | %.0f | %.2f | %d | %.0f | %.2f | %d | %s |\n",
+			res.Codec, res.Bytes, res.Encode.RecordsPerSec, res.Encode.MBPerSec, res.Encode.PeakHeap,
+			res.Decode.RecordsPerSec, res.Decode.MBPerSec, res.Decode.PeakHeap, fidelity)
+	}
+}
+
+func evolutionTable(w io.Writer, results []EvolutionResult) {
+	fmt.Fprintln(w, "| Codec | Record | Change | New reads old | Old reads new | Notes |")
+	fmt.Fprintln(w, "|---|---|---|---|---|---|")
+	for _, res := range results {
+		var notes []string
+		if res.BackwardNote != "" {
+			notes = append(notes, "new reads old: "+res.BackwardNote)
+		}
+		if res.ForwardNote != "" {
+			notes = append(notes, "old reads new: "+res.ForwardNote)
+		}
+		fmt.Fprintf(w, "| %s | %s | %s | %s | %s | %s |\n", res.Codec, res.Record, res.Change, res.Backward, res.Forward,
+			strings.Replace(strings.Join(notes, "<br>"), "|", "\\|", -1))
+	}
+}
+
+func parallelTable(w io.Writer, results []ParallelResult) {
+	fmt.Fprintln(w, "| Codec | Workers | Round trips/s | MB/s | Speedup | Efficiency |")
+	fmt.Fprintln(w, "|---|---:|---:|---:|---:|---:|")
+	for _, res := range results {
+		fmt.Fprintf(w, "| %s | %d | %.0f | %.2f | %.2f | %.2f |\n",
+			res.Codec, res.Workers, res.OpsPerSec, res.MBPerSec, res.Speedup, res.Efficiency)
+	}
+}
+
+func robustnessTable(w io.Writer, results []RobustnessResult) {
+	fmt.Fprintln(w, "| Codec | Mutation | Decoded | Errors | Panics | Hangs | Excessive allocs | Crashes |")
+	fmt.Fprintln(w, "|---|---|---:|---:|---:|---:|---:|---:|")
+	for _, res := range results {
+		fmt.Fprintf(w, "| %s | %s | %d | %d | %d | %d | %d | %d |\n",
+			res.Codec, res.Mutation, res.Decoded, res.Errors, res.Panics, res.Hangs, res.Allocs, res.Crashes)
+	}
+}
+
+func interopTable(w io.Writer, results []InteropResult) {
+	fmt.Fprintln(w, "| Codec | Schema | Artifact | Canonical JSON | Outcome | Golden | Notes |")
+	fmt.Fprintln(w, "|---|---|---|---|---|---|---|")
+	for _, res := range results {
+		fmt.Fprintf(w, "| %s | %s | %s | %s | %s | %s | %s |\n", res.Codec, res.Schema, res.Artifact, res.Canonical,
+			res.Outcome, res.Golden, strings.Replace(res.Note, "|", "\\|", -1))
+	}
+}
+
+func sizesTable(w io.Writer, sizes []SizeBreakdown) {
+	header := "| Codec | Total |"
+	align := "|---|---:|"
+	for _, f := range sizeFields {
+		header += " " + f.name + " |"
+		align += "---:|"
+	}
+	fmt.Fprintln(w, header+" Empty Test | Other | Notes |")
+	fmt.Fprintln(w, align+"---:|---:|---|")
+	for _, b := range sizes {
+		fmt.Fprintf(w, "| %s | %d |", b.Codec, b.Total)
+		for _, f := range b.Fields {
+			fmt.Fprintf(w, " %d (%.0f%%) |", f.Encoded, share(f.Encoded, b.Total))
+		}
+		fmt.Fprintf(w, " %d | %d | %s |\n", b.Empty, b.Other, strings.Replace(strings.Join(b.Notes, "<br>"), "|", "\\|", -1))
+	}
+	if len(sizes) > 0 {
+		raw := make([]string, len(sizes[0].Fields))
+		for i, f := range sizes[0].Fields {
+			raw[i] = fmt.Sprintf("%s %d", f.Field, f.Raw)
+		}
+		fmt.Fprintf(w, "\nRaw data in bytes (4 per number, string and blob lengths): %s. Notes are measured on the first test.\n", strings.Join(raw, ", "))
+	}
+}
+
+// edgeTable prints the outcomes as a case by codec matrix followed by
+// what went wrong in every cell that is not ok.
+func edgeTable(w io.Writer, results []EdgeResult) {
+	var names, cases []string
+	outcome := make(map[[2]string]string)
+	for _, res := range results {
+		if len(cases) == 0 || cases[len(cases)-1] != res.Case {
+			cases = append(cases, res.Case)
+		}
+		if len(cases) == 1 {
+			names = append(names, res.Codec)
+		}
+		outcome[[2]string{res.Case, res.Codec}] = res.Outcome
+	}
+	fmt.Fprintln(w, "| Case | "+strings.Join(names, " | ")+" |")
+	fmt.Fprintln(w, "|---|"+strings.Repeat("---|", len(names)))
+	for _, name := range cases {
+		fmt.Fprintf(w, "| %s |", name)
+		for _, codec := range names {
+			fmt.Fprintf(w, " %s |", outcome[[2]string{name, codec}])
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w)
+	escape := strings.NewReplacer("|", "\\|", "\n", " ", "\r", " ")
+	for _, res := range results {
+		if res.Outcome != edgeOK {
+			fmt.Fprintf(w, "* %s, %s: %s: %s\n", res.Codec, res.Case, res.Outcome, escape.Replace(res.Detail))
+		}
+	}
+}
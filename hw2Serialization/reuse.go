@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	fb "hw2Serialization/models/fb"
+
+	"github.com/fxamacker/cbor/v2"
+	flatbuffers "github.com/google/flatbuffers/go"
+	"github.com/vmihailenco/msgpack"
+	"go.mongodb.org/mongo-driver/bson"
+	"google.golang.org/protobuf/proto"
+)
+
+// reuseCodec is the steady-state variant of a codec: buffers, encoders
+// and decoders live in a sync.Pool and are reset instead of allocated on
+// every call, the way a long running service would use the library.
+//
+// Marshal hands out a copy of the encoded bytes, since the buffer they
+// were written to goes back to the pool for the next call; that copy of
+// exactly the output size is what a caller keeping the bytes pays anyway.
+type reuseCodec struct {
+	base      Codec
+	pool      *sync.Pool
+	marshal   func(state, v interface{}) ([]byte, error)
+	unmarshal func(state interface{}, data []byte, v interface{}) error // nil decodes with base
+}
+
+func (c *reuseCodec) Name() string { return c.base.Name() + "Reuse" }
+
+func (c *reuseCodec) Marshal(v interface{}) ([]byte, error) {
+	s := c.pool.Get()
+	defer c.pool.Put(s)
+	data, err := c.marshal(s, v)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), data...), nil
+}
+
+func (c *reuseCodec) Unmarshal(data []byte, v interface{}) error {
+	if c.unmarshal == nil {
+		return c.base.Unmarshal(data, v)
+	}
+	s := c.pool.Get()
+	defer c.pool.Put(s)
+	return c.unmarshal(s, data, v)
+}
+
+// encodeOnly reports whether the variant decodes with its naive codec,
+// as the library keeps nothing between calls to Unmarshal worth pooling.
+func (c *reuseCodec) encodeOnly() bool { return c.unmarshal == nil }
+
+func (c *reuseCodec) Model(t Test) interface{} { return modelOf(c.base, t) }
+func (c *reuseCodec) NewModel() interface{}    { return newModel(c.base) }
+func (c *reuseCodec) Test(v interface{}) Test  { return testOf(c.base, v) }
+
+func newPool(fn func() interface{}) *sync.Pool {
+	return &sync.Pool{New: fn}
+}
+
+// msgpState pairs a msgpack encoder and decoder with the buffer and
+// reader they are bound to; msgpack v4 encoders cannot be reset.
+type msgpState struct {
+	buf bytes.Buffer
+	enc *msgpack.Encoder
+	rd  bytes.Reader
+	dec *msgpack.Decoder
+}
+
+// jsonState binds a json.Encoder and json.Decoder to a buffer and reader
+// that are reset per call; neither can be reset itself. The decoder reads
+// its input as a stream, so a payload has to be consumed to the end, and a
+// decoder left in the middle of one by an error is replaced.
+type jsonState struct {
+	buf bytes.Buffer
+	enc *json.Encoder
+	rd  bytes.Reader
+	dec *json.Decoder
+}
+
+func (st *jsonState) decode(data []byte, v interface{}) error {
+	st.rd.Reset(data)
+	err := st.dec.Decode(v)
+	if err == nil {
+		// json.Unmarshal rejects anything after the value but whitespace
+		if _, tail := st.dec.Token(); tail != io.EOF {
+			err = fmt.Errorf("json: invalid data after top-level value")
+		}
+	}
+	if err != nil {
+		st.dec = json.NewDecoder(&st.rd)
+	}
+	return err
+}
+
+// cborState binds a cbor.Decoder to a reader reset per call, with the same
+// stream caveats as jsonState; encoding goes through MarshalToBuffer.
+type cborState struct {
+	buf bytes.Buffer
+	rd  bytes.Reader
+	dec *cbor.Decoder
+}
+
+func (st *cborState) decode(data []byte, v interface{}) error {
+	st.rd.Reset(data)
+	err := st.dec.Decode(v)
+	if err == nil {
+		// cbor.Unmarshal rejects extraneous data after the value
+		if tail := st.dec.Skip(); tail != io.EOF {
+			err = fmt.Errorf("cbor: extraneous data after the value")
+		}
+	}
+	if err != nil {
+		st.dec = cbor.NewDecoder(&st.rd)
+	}
+	return err
+}
+
+// reuseVariant returns the steady-state variant of c, or nil when the
+// library has nothing to reuse. Gob is left out as its encoders send type
+// descriptors once per stream, which is what -stream measures; Avro
+// already pools its writers and readers inside Marshal and Unmarshal, and
+// XML and YAML have no reusable state beyond what they allocate per value.
+func reuseVariant(c Codec) Codec {
+	switch c.(type) {
+	case jsonCodec:
+		// json.Marshal pools its buffer and copies the result out just
+		// like the variant, which shows what that pooling is worth
+		return &reuseCodec{base: c,
+			pool: newPool(func() interface{} {
+				s := &jsonState{}
+				s.enc = json.NewEncoder(&s.buf)
+				s.dec = json.NewDecoder(&s.rd)
+				return s
+			}),
+			marshal: func(s, v interface{}) ([]byte, error) {
+				st := s.(*jsonState)
+				st.buf.Reset()
+				if err := st.enc.Encode(v); err != nil {
+					return nil, err
+				}
+				return bytes.TrimSuffix(st.buf.Bytes(), []byte("\n")), nil
+			},
+			unmarshal: func(s interface{}, data []byte, v interface{}) error {
+				return s.(*jsonState).decode(data, v)
+			}}
+	case *protoCodec:
+		// proto.Unmarshal keeps no state between calls, so only encoding
+		// has something to reuse
+		return &reuseCodec{base: c, pool: newPool(func() interface{} { return new([]byte) }),
+			marshal: func(s, v interface{}) ([]byte, error) {
+				m, ok := v.(proto.Message)
+				if !ok {
+					return nil, fmt.Errorf("proto: %T is not a proto.Message", v)
+				}
+				buf := s.(*[]byte)
+				out, err := proto.MarshalOptions{}.MarshalAppend((*buf)[:0], m)
+				if err == nil {
+					*buf = out
+				}
+				return out, err
+			}}
+	case msgpCodec:
+		return &reuseCodec{base: c,
+			pool: newPool(func() interface{} {
+				s := &msgpState{}
+				s.enc = msgpack.NewEncoder(&s.buf)
+				s.dec = msgpack.NewDecoder(&s.rd)
+				return s
+			}),
+			marshal: func(s, v interface{}) ([]byte, error) {
+				st := s.(*msgpState)
+				st.buf.Reset()
+				err := st.enc.Encode(v)
+				return st.buf.Bytes(), err
+			},
+			unmarshal: func(s interface{}, data []byte, v interface{}) error {
+				st := s.(*msgpState)
+				st.rd.Reset(data)
+				st.dec.Reset(&st.rd)
+				return st.dec.Decode(v)
+			}}
+	case cborCodec:
+		return &reuseCodec{base: c,
+			pool: newPool(func() interface{} {
+				s := &cborState{}
+				s.dec = cbor.NewDecoder(&s.rd)
+				return s
+			}),
+			marshal: func(s, v interface{}) ([]byte, error) {
+				st := s.(*cborState)
+				st.buf.Reset()
+				err := cbor.MarshalToBuffer(v, &st.buf)
+				return st.buf.Bytes(), err
+			},
+			unmarshal: func(s interface{}, data []byte, v interface{}) error {
+				return s.(*cborState).decode(data, v)
+			}}
+	case bsonCodec:
+		// bson.Unmarshal already pools its bson.Decoder
+		return &reuseCodec{base: c, pool: newPool(func() interface{} { return new([]byte) }),
+			marshal: func(s, v interface{}) ([]byte, error) {
+				buf := s.(*[]byte)
+				out, err := bson.MarshalAppend((*buf)[:0], v)
+				if err == nil {
+					*buf = out
+				}
+				return out, err
+			}}
+	case flatbuffersCodec:
+		// reading a FlatBuffer allocates only the Test it is copied into
+		return &reuseCodec{base: c, pool: newPool(func() interface{} { return flatbuffers.NewBuilder(1024) }),
+			marshal: func(s, v interface{}) ([]byte, error) {
+				t, err := asTest(v)
+				if err != nil {
+					return nil, err
+				}
+				b := s.(*flatbuffers.Builder)
+				b.Reset()
+				fb.FinishTestBuffer(b, buildFBTest(b, t))
+				return b.FinishedBytes(), nil
+			}}
+	}
+	return nil
+}
+
+// withReuse puts the steady-state variant of every codec that has one
+// right after it, so reports show both numbers side by side.
+func withReuse(enabled []Codec) []Codec {
+	var out []Codec
+	for _, c := range enabled {
+		out = append(out, c)
+		if r := reuseVariant(c); r != nil {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// reuseEncodeOnly lists the variants among enabled that reuse state when
+// encoding only, so reports can say their decode numbers are the naive ones.
+func reuseEncodeOnly(enabled []Codec) []string {
+	var names []string
+	for _, c := range enabled {
+		if r, ok := c.(*reuseCodec); ok && r.encodeOnly() {
+			names = append(names, r.Name())
+		}
+	}
+	return names
+}
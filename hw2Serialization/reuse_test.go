@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestReuseVariants checks that the steady-state variants write what the
+// naive codecs read and the other way round, also once their pooled state
+// holds the previous payload, and that the bytes they return stay intact
+// after the next Marshal.
+func TestReuseVariants(t *testing.T) {
+	for _, c := range codecs {
+		r := reuseVariant(c)
+		if r == nil {
+			continue
+		}
+		for i := 0; i < 2; i++ {
+			for _, p := range benchPayloads(t) {
+				data, err := r.Marshal(modelOf(r, p.test))
+				if err != nil {
+					t.Fatalf("%s marshal %s: %v", r.Name(), p.name, err)
+				}
+				kept := append([]byte(nil), data...)
+				if _, err := r.Marshal(modelOf(r, Test{ID: 1, Name: "overwrite"})); err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(data, kept) {
+					t.Fatalf("%s: the next Marshal overwrote the bytes of %s", r.Name(), p.name)
+				}
+				v := newModel(c)
+				if err := c.Unmarshal(data, v); err != nil {
+					t.Fatalf("%s cannot read %s of %s: %v", c.Name(), p.name, r.Name(), err)
+				}
+				if diffs := verifyRoundTrip(c, p.test, v, 1e-6); len(diffs) > 0 {
+					t.Errorf("%s %s: %v", r.Name(), p.name, diffs)
+				}
+
+				data, err = c.Marshal(modelOf(c, p.test))
+				if err != nil {
+					t.Fatal(err)
+				}
+				v = newModel(r)
+				if err := r.Unmarshal(data, v); err != nil {
+					t.Fatalf("%s cannot read %s of %s: %v", r.Name(), p.name, c.Name(), err)
+				}
+				if diffs := verifyRoundTrip(r, p.test, v, 1e-6); len(diffs) > 0 {
+					t.Errorf("%s %s: %v", r.Name(), p.name, diffs)
+				}
+			}
+		}
+	}
+}
+
+// TestReuseDecoders checks that the pooled decoders reject a truncated
+// payload or one followed by more data whenever the naive Unmarshal does,
+// and that the pooled state still decodes the next payload after it.
+func TestReuseDecoders(t *testing.T) {
+	for _, c := range codecs {
+		r, _ := reuseVariant(c).(*reuseCodec)
+		if r == nil || r.encodeOnly() {
+			continue
+		}
+		want := benchPayloads(t)[0].test
+		data, err := c.Marshal(modelOf(c, want))
+		if err != nil {
+			t.Fatal(err)
+		}
+		bad := map[string][]byte{
+			"truncated": data[:len(data)/2],
+			"trailing":  append(append([]byte(nil), data...), data...),
+		}
+		for name, b := range bad {
+			naive := c.Unmarshal(b, newModel(c))
+			if err := r.Unmarshal(b, newModel(r)); err == nil && naive != nil {
+				t.Errorf("%s accepted a %s payload that %s rejects: %v", r.Name(), name, c.Name(), naive)
+			}
+			v := newModel(r)
+			if err := r.Unmarshal(data, v); err != nil {
+				t.Fatalf("%s after a %s payload: %v", r.Name(), name, err)
+			}
+			if diffs := verifyRoundTrip(r, want, v, 1e-6); len(diffs) > 0 {
+				t.Errorf("%s after a %s payload: %v", r.Name(), name, diffs)
+			}
+		}
+	}
+}
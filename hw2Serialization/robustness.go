@@ -0,0 +1,387 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// robustTimeout is how long one decode may run before it counts as a hang.
+	robustTimeout = 2 * time.Second
+	// robustAllocLimit is how much one decode of a small mutated input
+	// may allocate before it counts as excessive.
+	robustAllocLimit = 16 << 20
+	// robustPrefix is the length written by the "length" mutation: large
+	// enough to expose decoders that trust it, small enough not to get
+	// the harness itself killed when one does.
+	robustPrefix = 1 << 26
+
+	crashersDir = "files/crashers"
+)
+
+// mutator corrupts a copy of valid encoded bytes.
+type mutator struct {
+	name  string
+	apply func(rnd *rand.Rand, data []byte) []byte
+}
+
+var mutators = []mutator{
+	{"truncate", func(rnd *rand.Rand, data []byte) []byte {
+		return append([]byte{}, data[:rnd.Intn(len(data))]...)
+	}},
+	{"bitflip", func(rnd *rand.Rand, data []byte) []byte {
+		out := append([]byte{}, data...)
+		for n := 1 + rnd.Intn(8); n > 0; n-- {
+			out[rnd.Intn(len(out))] ^= 1 << uint(rnd.Intn(8))
+		}
+		return out
+	}},
+	// length overwrites bytes with a huge length as a varint or a 32 bit
+	// integer of either byte order, favouring the header where most
+	// formats keep their first length prefix.
+	{"length", func(rnd *rand.Rand, data []byte) []byte {
+		prefix := make([]byte, binary.MaxVarintLen64)
+		switch rnd.Intn(3) {
+		case 0:
+			prefix = prefix[:binary.PutUvarint(prefix, robustPrefix)]
+		case 1:
+			prefix = prefix[:4]
+			binary.BigEndian.PutUint32(prefix, robustPrefix)
+		default:
+			prefix = prefix[:4]
+			binary.LittleEndian.PutUint32(prefix, robustPrefix)
+		}
+		out := append([]byte{}, data...)
+		at := rnd.Intn(len(out))
+		if rnd.Intn(2) == 0 && len(out) > 16 {
+			at = rnd.Intn(16)
+		}
+		copy(out[at:], prefix)
+		return out
+	}},
+}
+
+// Outcomes of decoding one mutated input.
+const (
+	robustDecoded = "decoded" // accepted, possibly as a different value
+	robustError   = "error"   // rejected with an error, as it should be
+	robustPanic   = "panic"
+	robustHang    = "hang"
+	robustAlloc   = "alloc" // allocated more than robustAllocLimit
+)
+
+// robustDecode decodes data with c in its own goroutine, so panics are
+// recovered and hangs are detected. A hung decoder keeps its goroutine
+// until the process exits.
+func robustDecode(c Codec, data []byte) (outcome, detail string) {
+	return robustDecodeInto(c, data, newModel(c))
+}
+
+// robustDecodeInto is robustDecode into v, for callers that need the value.
+func robustDecodeInto(c Codec, data []byte, v interface{}) (outcome, detail string) {
+	type result struct {
+		err   error
+		panic interface{}
+		alloc uint64
+	}
+	done := make(chan result, 1)
+	go func() {
+		var res result
+		defer func() {
+			if r := recover(); r != nil {
+				res.panic = r
+			}
+			done <- res
+		}()
+		var m0, m1 runtime.MemStats
+		runtime.ReadMemStats(&m0)
+		res.err = c.Unmarshal(data, v)
+		runtime.ReadMemStats(&m1)
+		res.alloc = m1.TotalAlloc - m0.TotalAlloc
+	}()
+
+	select {
+	case res := <-done:
+		switch {
+		case res.panic != nil:
+			return robustPanic, fmt.Sprint(res.panic)
+		case res.alloc > robustAllocLimit:
+			return robustAlloc, fmt.Sprintf("%d bytes allocated for %d bytes of input", res.alloc, len(data))
+		case res.err != nil:
+			return robustError, res.err.Error()
+		}
+		return robustDecoded, ""
+	case <-time.After(robustTimeout):
+		return robustHang, fmt.Sprintf("no result after %v", robustTimeout)
+	}
+}
+
+// RobustnessResult counts the outcomes of one mutation kind against one
+// codec. Crashes are fatal errors no recover can catch, such as running
+// out of memory or stack. Crashers lists the saved inputs that crashed,
+// panicked, hung or allocated too much.
+type RobustnessResult struct {
+	Codec    string   `json:"codec"`
+	Mutation string   `json:"mutation"`
+	Runs     int      `json:"runs"`
+	Decoded  int      `json:"decoded"`
+	Errors   int      `json:"errors"`
+	Panics   int      `json:"panics"`
+	Hangs    int      `json:"hangs"`
+	Allocs   int      `json:"allocs"`
+	Crashes  int      `json:"crashes"`
+	Crashers []string `json:"crashers,omitempty"`
+}
+
+// robustCrash is the outcome of an input that killed the decoding process.
+const robustCrash = "crash"
+
+// robustBase returns the valid input to mutate: the artifact a previous
+// run left in files/, or t freshly encoded.
+func robustBase(c Codec, t Test) ([]byte, error) {
+	if data, err := ioutil.ReadFile(artifactPath(c)); err == nil && len(data) > 0 {
+		return data, nil
+	}
+	return c.Marshal(modelOf(c, t))
+}
+
+// mutate returns the n inputs mutation m derives from base with seed,
+// the same sequence in the harness and in its child processes.
+func mutate(m mutator, base []byte, seed int64, n int) [][]byte {
+	rnd := rand.New(rand.NewSource(seed))
+	inputs := make([][]byte, n)
+	for i := range inputs {
+		inputs[i] = m.apply(rnd, base)
+	}
+	return inputs
+}
+
+func lookupMutator(name string) (mutator, bool) {
+	for _, m := range mutators {
+		if m.name == name {
+			return m, true
+		}
+	}
+	return mutator{}, false
+}
+
+// robustChildCmd implements the hidden `main robustness-child` subcommand.
+// Decoders run in a child process because some failures (out of memory,
+// stack overflow) kill the process instead of panicking. The child decodes
+// inputs from -from on and prints "start i" before and "i outcome detail"
+// after each one, and exits after a hang so its goroutine does not keep
+// burning CPU.
+func robustChildCmd(args []string) int {
+	fs := flag.NewFlagSet("robustness-child", flag.ExitOnError)
+	codec := fs.String("codec", "", "")
+	mutation := fs.String("mutation", "", "")
+	basePath := fs.String("base", "", "")
+	seed := fs.Int64("seed", 0, "")
+	n := fs.Int("n", 0, "")
+	from := fs.Int("from", 0, "")
+	fs.Parse(args)
+
+	c := lookupCodec(*codec)
+	m, ok := lookupMutator(*mutation)
+	if c == nil || !ok {
+		fmt.Fprintf(os.Stderr, "unknown codec %q or mutation %q\n", *codec, *mutation)
+		return 2
+	}
+	if s, ok := c.(Setuper); ok {
+		if err := s.Setup(); err != nil {
+			fmt.Fprintln(os.Stderr, c.Name(), "setup error:", err)
+			return 2
+		}
+	}
+	base, err := ioutil.ReadFile(*basePath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	// Decode the valid input once so codecs that build their decoders
+	// lazily do not charge that to the first mutated input.
+	robustDecode(c, base)
+
+	for i, data := range mutate(m, base, *seed, *n) {
+		if i < *from {
+			continue
+		}
+		fmt.Printf("start %d\n", i)
+		outcome, detail := robustDecode(c, data)
+		fmt.Printf("%d %s %s\n", i, outcome, strings.Replace(detail, "\n", " ", -1))
+		if outcome == robustHang {
+			return 3
+		}
+	}
+	return 0
+}
+
+// robustChild runs one child process over inputs from on. It returns the
+// outcome of every input the child finished and, if the child died, the
+// index of the input it was decoding and why.
+func robustChild(exe string, c Codec, m mutator, basePath string, seed int64, n, from int) (outcomes map[int][2]string, crashed int, reason string, err error) {
+	cmd := exec.Command(exe, "robustness-child", "-codec", c.Name(), "-mutation", m.name, "-base", basePath,
+		"-seed", strconv.FormatInt(seed, 10), "-n", strconv.Itoa(n), "-from", strconv.Itoa(from))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, -1, "", err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, -1, "", err
+	}
+
+	outcomes = make(map[int][2]string)
+	started := -1
+	sc := bufio.NewScanner(stdout)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		fields := strings.SplitN(sc.Text(), " ", 3)
+		if len(fields) < 2 {
+			continue
+		}
+		if fields[0] == "start" {
+			started, _ = strconv.Atoi(fields[1])
+			continue
+		}
+		i, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		detail := ""
+		if len(fields) == 3 {
+			detail = fields[2]
+		}
+		outcomes[i] = [2]string{fields[1], detail}
+	}
+	waitErr := cmd.Wait()
+
+	if _, done := outcomes[started]; started >= 0 && !done {
+		return outcomes, started, fatalReason(stderr.String()), nil
+	}
+	if waitErr != nil && len(outcomes) == 0 {
+		return nil, -1, "", fmt.Errorf("%v: %s", waitErr, fatalReason(stderr.String()))
+	}
+	return outcomes, -1, "", nil
+}
+
+// fatalReason picks the "fatal error: ..." line out of a Go crash dump.
+func fatalReason(stderr string) string {
+	for _, line := range strings.Split(stderr, "\n") {
+		if strings.HasPrefix(line, "fatal error:") || strings.HasPrefix(line, "runtime:") {
+			return line
+		}
+	}
+	if i := strings.IndexByte(stderr, '\n'); i >= 0 {
+		return stderr[:i]
+	}
+	return stderr
+}
+
+// runRobustness feeds n mutations of every kind to every enabled codec
+// and returns the process exit code: 1 if any decoder crashed, panicked
+// or hung.
+func runRobustness(enabled []Codec, t Test, seed int64, n int, report *Report) int {
+	if err := os.MkdirAll(crashersDir, 0755); err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	code := 0
+	for _, c := range enabled {
+		base, err := robustBase(c, t)
+		if err != nil || len(base) == 0 {
+			fmt.Printf("%s: no valid input to mutate: %v\n\n", c.Name(), err)
+			continue
+		}
+		basePath := filepath.Join(crashersDir, c.Name()+".base")
+		if err := ioutil.WriteFile(basePath, base, 0644); err != nil {
+			fmt.Println("writing error", err)
+			return 1
+		}
+		fmt.Printf("%s (%d bytes)\n  %-9s %8s %8s %8s %8s %8s %8s\n", c.Name(), len(base),
+			"mutation", "decoded", "errors", "panics", "hangs", "allocs", "crashes")
+
+		for _, m := range mutators {
+			res := RobustnessResult{Codec: c.Name(), Mutation: m.name, Runs: n}
+			inputs := mutate(m, base, seed, n)
+			outcomes := make(map[int][2]string, n)
+			for from := 0; from < n; {
+				done, crashed, reason, err := robustChild(exe, c, m, basePath, seed, n, from)
+				if err != nil {
+					fmt.Printf("  %s: %v\n", m.name, err)
+					break
+				}
+				next := n
+				for i, o := range done {
+					outcomes[i] = o
+					if o[0] == robustHang && i+1 < next {
+						next = i + 1
+					}
+				}
+				if crashed >= 0 {
+					outcomes[crashed] = [2]string{robustCrash, reason}
+					next = crashed + 1
+				}
+				from = next
+			}
+
+			for i := 0; i < n; i++ {
+				o, ok := outcomes[i]
+				if !ok {
+					continue
+				}
+				switch o[0] {
+				case robustDecoded:
+					res.Decoded++
+					continue
+				case robustError:
+					res.Errors++
+					continue
+				case robustPanic:
+					res.Panics++
+				case robustHang:
+					res.Hangs++
+				case robustAlloc:
+					res.Allocs++
+				case robustCrash:
+					res.Crashes++
+				}
+				path := filepath.Join(crashersDir, fmt.Sprintf("%s-%s-%d.bin", c.Name(), m.name, i))
+				if err := ioutil.WriteFile(path, inputs[i], 0644); err != nil {
+					fmt.Println("writing error", err)
+				}
+				res.Crashers = append(res.Crashers, path)
+				if len(res.Crashers) == 1 {
+					fmt.Printf("  %s %s: %s (%s)\n", m.name, o[0], o[1], path)
+				}
+			}
+			if res.Panics > 0 || res.Hangs > 0 || res.Crashes > 0 {
+				code = 1
+			}
+			report.Robustness = append(report.Robustness, res)
+			fmt.Printf("  %-9s %8d %8d %8d %8d %8d %8d\n", m.name, res.Decoded, res.Errors, res.Panics, res.Hangs, res.Allocs, res.Crashes)
+		}
+		fmt.Println()
+	}
+	return code
+}
@@ -0,0 +1,879 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/hamba/avro/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func init() {
+	// the generic trees are sent through interfaces
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+}
+
+// schemaNode is a user schema reduced to what generating, encoding and
+// checking generic values needs. It is built from a .proto message or an
+// Avro schema; recursive types point back to their own node.
+type schemaNode struct {
+	kind    string        // record, list, map, optional, enum, bytes, string, bool, int32, int64, uint32, uint64, float32 or float64
+	name    string        // record and enum name
+	fields  []schemaField // record
+	elem    *schemaNode   // list, map and optional
+	key     string        // scalar kind of map keys
+	symbols []string      // enum
+	numbers []int32       // enum, the proto value numbers
+	size    int           // bytes of an Avro fixed, 0 for variable
+}
+
+type schemaField struct {
+	name  string
+	node  *schemaNode
+	oneof string // proto oneof the field belongs to
+}
+
+var protoKinds = map[protoreflect.Kind]string{
+	protoreflect.BoolKind:     "bool",
+	protoreflect.Int32Kind:    "int32",
+	protoreflect.Sint32Kind:   "int32",
+	protoreflect.Sfixed32Kind: "int32",
+	protoreflect.Int64Kind:    "int64",
+	protoreflect.Sint64Kind:   "int64",
+	protoreflect.Sfixed64Kind: "int64",
+	protoreflect.Uint32Kind:   "uint32",
+	protoreflect.Fixed32Kind:  "uint32",
+	protoreflect.Uint64Kind:   "uint64",
+	protoreflect.Fixed64Kind:  "uint64",
+	protoreflect.FloatKind:    "float32",
+	protoreflect.DoubleKind:   "float64",
+	protoreflect.StringKind:   "string",
+	protoreflect.BytesKind:    "bytes",
+}
+
+// protoNode converts a message descriptor; memo holds the records
+// already converted so recursion ends.
+func protoNode(md protoreflect.MessageDescriptor, memo map[protoreflect.FullName]*schemaNode) *schemaNode {
+	if n, ok := memo[md.FullName()]; ok {
+		return n
+	}
+	n := &schemaNode{kind: "record", name: string(md.FullName())}
+	memo[md.FullName()] = n
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		f := schemaField{name: string(fd.Name())}
+		switch {
+		case fd.IsMap():
+			f.node = &schemaNode{kind: "map", key: protoKinds[fd.MapKey().Kind()], elem: protoValueNode(fd.MapValue(), memo)}
+		case fd.IsList():
+			f.node = &schemaNode{kind: "list", elem: protoValueNode(fd, memo)}
+		case fd.HasPresence():
+			f.node = &schemaNode{kind: "optional", elem: protoValueNode(fd, memo)}
+		default:
+			f.node = protoValueNode(fd, memo)
+		}
+		if od := fd.ContainingOneof(); od != nil && !od.IsSynthetic() {
+			f.oneof = string(od.Name())
+		}
+		n.fields = append(n.fields, f)
+	}
+	return n
+}
+
+func protoValueNode(fd protoreflect.FieldDescriptor, memo map[protoreflect.FullName]*schemaNode) *schemaNode {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return protoNode(fd.Message(), memo)
+	case protoreflect.EnumKind:
+		n := &schemaNode{kind: "enum", name: string(fd.Enum().FullName())}
+		values := fd.Enum().Values()
+		for i := 0; i < values.Len(); i++ {
+			n.symbols = append(n.symbols, string(values.Get(i).Name()))
+			n.numbers = append(n.numbers, int32(values.Get(i).Number()))
+		}
+		return n
+	}
+	return &schemaNode{kind: protoKinds[fd.Kind()]}
+}
+
+var avroKinds = map[avro.Type]string{
+	avro.Boolean: "bool",
+	avro.Int:     "int32",
+	avro.Long:    "int64",
+	avro.Float:   "float32",
+	avro.Double:  "float64",
+	avro.String:  "string",
+	avro.Bytes:   "bytes",
+}
+
+// avroNode converts an Avro schema. Only unions of null and one other
+// type are supported, as every other format can express those.
+func avroNode(s avro.Schema, memo map[string]*schemaNode) (*schemaNode, error) {
+	switch s := s.(type) {
+	case *avro.RefSchema:
+		return avroNode(s.Schema(), memo)
+	case *avro.RecordSchema:
+		if n, ok := memo[s.FullName()]; ok {
+			return n, nil
+		}
+		n := &schemaNode{kind: "record", name: s.FullName()}
+		memo[s.FullName()] = n
+		for _, f := range s.Fields() {
+			fn, err := avroNode(f.Type(), memo)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %v", s.FullName(), f.Name(), err)
+			}
+			n.fields = append(n.fields, schemaField{name: f.Name(), node: fn})
+		}
+		return n, nil
+	case *avro.ArraySchema:
+		elem, err := avroNode(s.Items(), memo)
+		return &schemaNode{kind: "list", elem: elem}, err
+	case *avro.MapSchema:
+		elem, err := avroNode(s.Values(), memo)
+		return &schemaNode{kind: "map", key: "string", elem: elem}, err
+	case *avro.UnionSchema:
+		types := s.Types()
+		if len(types) != 2 || !s.Nullable() {
+			return nil, fmt.Errorf("only unions of null and one type are supported")
+		}
+		other := types[0]
+		if other.Type() == avro.Null {
+			other = types[1]
+		}
+		elem, err := avroNode(other, memo)
+		return &schemaNode{kind: "optional", elem: elem}, err
+	case *avro.EnumSchema:
+		return &schemaNode{kind: "enum", name: s.FullName(), symbols: s.Symbols()}, nil
+	case *avro.FixedSchema:
+		return &schemaNode{kind: "bytes", size: s.Size()}, nil
+	}
+	if kind, ok := avroKinds[s.Type()]; ok {
+		return &schemaNode{kind: kind}, nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", s.Type())
+}
+
+// schemaGen draws random values conforming to a schema, sized by a Shape
+// the same way generateTest sizes Test: Tests for lists of records at the
+// first level, Children below, Numbers and Floats for scalar lists,
+// Labels for maps, Blob for bytes and Optional for optional scalars.
+// Depth limits how often a record may nest inside itself; optional
+// records are always filled while that limit allows.
+type schemaGen struct {
+	rnd   *rand.Rand
+	shape Shape
+	depth map[*schemaNode]int
+}
+
+// value returns nil for an absent optional value and false when a record
+// cannot be generated without nesting deeper than allowed.
+func (g *schemaGen) value(n *schemaNode) (interface{}, bool) {
+	switch n.kind {
+	case "record":
+		if g.depth[n] > g.shape.Depth {
+			return nil, false
+		}
+		g.depth[n]++
+		defer func() { g.depth[n]-- }()
+
+		chosen := make(map[string]string)
+		for _, f := range n.fields {
+			if f.oneof != "" {
+				if _, ok := chosen[f.oneof]; !ok || g.rnd.Intn(2) == 0 {
+					chosen[f.oneof] = f.name
+				}
+			}
+		}
+		out := make(map[string]interface{}, len(n.fields))
+		for _, f := range n.fields {
+			if f.oneof != "" && chosen[f.oneof] != f.name {
+				continue
+			}
+			v, ok := g.value(f.node)
+			if !ok {
+				return nil, false
+			}
+			if v != nil {
+				out[f.name] = v
+			}
+		}
+		return out, true
+	case "list":
+		r := g.shape.Numbers
+		switch {
+		case n.elem.kind == "record" && g.depth[n.elem] > 0:
+			r = g.shape.Children
+		case n.elem.kind == "record":
+			r = g.shape.Tests
+		case n.elem.kind == "float32" || n.elem.kind == "float64":
+			r = g.shape.Floats
+		}
+		items := make([]interface{}, 0, r.Max)
+		for i := r.pick(g.rnd); i > 0; i-- {
+			v, ok := g.value(n.elem)
+			if !ok {
+				break
+			}
+			items = append(items, v)
+		}
+		return items, true
+	case "map":
+		out := make(map[string]interface{})
+		for i := g.shape.Labels.pick(g.rnd) - 1; i >= 0; i-- {
+			v, ok := g.value(n.elem)
+			if !ok {
+				break
+			}
+			key := "key" + strconv.Itoa(i)
+			switch n.key {
+			case "bool":
+				key = strconv.FormatBool(i%2 == 1)
+			case "int32", "int64", "uint32", "uint64":
+				key = strconv.Itoa(i)
+			}
+			out[key] = v
+		}
+		return out, true
+	case "optional":
+		if n.elem.kind == "record" || g.rnd.Float64() < g.shape.Optional {
+			if v, ok := g.value(n.elem); ok {
+				return v, true
+			}
+		}
+		return nil, true
+	case "enum":
+		return n.symbols[g.rnd.Intn(len(n.symbols))], true
+	case "bytes":
+		b := make([]byte, n.size)
+		if n.size == 0 {
+			b = make([]byte, g.shape.Blob.pick(g.rnd))
+		}
+		g.rnd.Read(b)
+		return b, true
+	case "string":
+		return randString(g.rnd, g.shape.Strings), true
+	case "bool":
+		return g.rnd.Intn(2) == 1, true
+	case "int32":
+		return int32(g.rnd.Uint32()), true
+	case "int64":
+		return int64(g.rnd.Uint64()), true
+	case "uint32":
+		return g.rnd.Uint32(), true
+	case "uint64":
+		return g.rnd.Uint64(), true
+	case "float32":
+		return g.rnd.Float32(), true
+	case "float64":
+		return g.rnd.Float64(), true
+	}
+	return nil, false
+}
+
+// coerce converts what a generic decoder produced back to the types the
+// schema says, so values from every format compare equal: numbers of any
+// Go type or json.Number, maps with interface{} keys, BSON documents and
+// bytes given as base64 (JSON), integer lists (YAML) or arrays (fixed).
+// Absent optional values are left out, absent lists and maps are empty.
+func coerce(n *schemaNode, v interface{}, base64Bytes bool) (interface{}, error) {
+	rv := reflect.ValueOf(v)
+	switch n.kind {
+	case "record":
+		m, err := stringMap(v)
+		if err != nil {
+			return nil, err
+		}
+		out := make(map[string]interface{}, len(n.fields))
+		for _, f := range n.fields {
+			fv, ok := m[f.name]
+			if !ok || fv == nil {
+				switch f.node.kind {
+				case "optional":
+					continue
+				case "list":
+					fv = []interface{}{}
+				case "map":
+					fv = map[string]interface{}{}
+				default:
+					return nil, fmt.Errorf("%s: missing", f.name)
+				}
+			}
+			c, err := coerce(f.node, fv, base64Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", f.name, err)
+			}
+			out[f.name] = c
+		}
+		return out, nil
+	case "list":
+		if rv.Kind() != reflect.Slice {
+			return nil, fmt.Errorf("%T is not a list", v)
+		}
+		out := make([]interface{}, rv.Len())
+		for i := range out {
+			c, err := coerce(n.elem, rv.Index(i).Interface(), base64Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %v", i, err)
+			}
+			out[i] = c
+		}
+		return out, nil
+	case "map":
+		m, err := stringMap(v)
+		if err != nil {
+			return nil, err
+		}
+		out := make(map[string]interface{}, len(m))
+		for k, item := range m {
+			c, err := coerce(n.elem, item, base64Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("[%q]: %v", k, err)
+			}
+			out[k] = c
+		}
+		return out, nil
+	case "optional":
+		return coerce(n.elem, v, base64Bytes)
+	case "enum":
+		if s, ok := v.(string); ok {
+			for _, sym := range n.symbols {
+				if sym == s {
+					return s, nil
+				}
+			}
+			return nil, fmt.Errorf("unknown symbol %q", s)
+		}
+		num, err := coerceScalar("int32", v)
+		if err != nil {
+			return nil, err
+		}
+		for i, number := range n.numbers {
+			if number == num.(int32) {
+				return n.symbols[i], nil
+			}
+		}
+		return nil, fmt.Errorf("unknown enum value %v", v)
+	case "bytes":
+		switch b := v.(type) {
+		case []byte:
+			return b, nil
+		case primitive.Binary:
+			return b.Data, nil
+		case string:
+			if base64Bytes {
+				return base64.StdEncoding.DecodeString(b)
+			}
+			return []byte(b), nil
+		}
+		if rv.Kind() == reflect.Array || rv.Kind() == reflect.Slice {
+			out := make([]byte, rv.Len())
+			for i := range out {
+				c, err := coerceScalar("uint32", rv.Index(i).Interface())
+				if err != nil || c.(uint32) > math.MaxUint8 {
+					return nil, fmt.Errorf("[%d]: %v is not a byte", i, rv.Index(i).Interface())
+				}
+				out[i] = byte(c.(uint32))
+			}
+			return out, nil
+		}
+		return nil, fmt.Errorf("%T is not bytes", v)
+	}
+	return coerceScalar(n.kind, v)
+}
+
+// stringMap accepts the map types generic decoders produce.
+func stringMap(v interface{}) (map[string]interface{}, error) {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		return m, nil
+	case primitive.M:
+		return m, nil
+	case primitive.D:
+		return m.Map(), nil
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Map {
+		return nil, fmt.Errorf("%T is not a map", v)
+	}
+	out := make(map[string]interface{}, rv.Len())
+	it := rv.MapRange()
+	for it.Next() {
+		out[fmt.Sprint(it.Key().Interface())] = it.Value().Interface()
+	}
+	return out, nil
+}
+
+// coerceScalar converts v to the Go type of kind, failing rather than
+// rounding, overflowing or truncating.
+func coerceScalar(kind string, v interface{}) (interface{}, error) {
+	switch kind {
+	case "string":
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+		return nil, fmt.Errorf("%T is not a string", v)
+	case "bool":
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+		return nil, fmt.Errorf("%T is not a bool", v)
+	}
+
+	var f float64
+	var i int64
+	var u uint64
+	isInt, isUint := false, false
+	switch x := v.(type) {
+	case json.Number:
+		if n, err := strconv.ParseInt(string(x), 10, 64); err == nil {
+			i, isInt = n, true
+		} else if n, err := strconv.ParseUint(string(x), 10, 64); err == nil {
+			u, isUint = n, true
+		} else if f, err = strconv.ParseFloat(string(x), 64); err != nil {
+			return nil, err
+		}
+	default:
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			i, isInt = rv.Int(), true
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			u, isUint = rv.Uint(), true
+		case reflect.Float32, reflect.Float64:
+			f = rv.Float()
+		default:
+			return nil, fmt.Errorf("%T is not a number", v)
+		}
+	}
+	switch {
+	case isInt:
+		f, u = float64(i), uint64(i)
+	case isUint:
+		f, i = float64(u), int64(u)
+	}
+
+	exact := func(ok bool, out interface{}) (interface{}, error) {
+		if !ok {
+			return nil, fmt.Errorf("%v does not fit %s", v, kind)
+		}
+		return out, nil
+	}
+	switch kind {
+	case "float32":
+		// decimal text rarely holds a float32 exactly, the nearest one is meant
+		return float32(f), nil
+	case "float64":
+		return f, nil
+	case "int32":
+		if !isInt && !isUint {
+			return exact(f == math.Trunc(f) && f >= math.MinInt32 && f <= math.MaxInt32, int32(f))
+		}
+		return exact((isInt || u <= math.MaxInt64) && i >= math.MinInt32 && i <= math.MaxInt32, int32(i))
+	case "int64":
+		if !isInt && !isUint {
+			return exact(f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64, int64(f))
+		}
+		return exact(isInt || u <= math.MaxInt64, i)
+	case "uint32":
+		if !isInt && !isUint {
+			return exact(f == math.Trunc(f) && f >= 0 && f <= math.MaxUint32, uint32(f))
+		}
+		return exact((isUint || i >= 0) && u <= math.MaxUint32, uint32(u))
+	case "uint64":
+		if !isInt && !isUint {
+			return exact(f == math.Trunc(f) && f >= 0 && f < math.MaxUint64, uint64(f))
+		}
+		return exact(isUint || i >= 0, u)
+	}
+	return nil, fmt.Errorf("unknown kind %s", kind)
+}
+
+// protoMessage builds a dynamic message from a tree already coerced to
+// the message's own node.
+func protoMessage(m protoreflect.Message, n *schemaNode, tree map[string]interface{}) error {
+	fields := m.Descriptor().Fields()
+	for _, f := range n.fields {
+		v, ok := tree[f.name]
+		if !ok {
+			continue
+		}
+		fd := fields.ByName(protoreflect.Name(f.name))
+		switch f.node.kind {
+		case "list":
+			list := m.Mutable(fd).List()
+			for _, item := range v.([]interface{}) {
+				pv, err := protoFieldValue(list.NewElement, fd, f.node.elem, item)
+				if err != nil {
+					return err
+				}
+				list.Append(pv)
+			}
+		case "map":
+			entries := m.Mutable(fd).Map()
+			for k, item := range v.(map[string]interface{}) {
+				key, err := protoMapKey(fd.MapKey(), f.node.key, k)
+				if err != nil {
+					return err
+				}
+				pv, err := protoFieldValue(entries.NewValue, fd.MapValue(), f.node.elem, item)
+				if err != nil {
+					return err
+				}
+				entries.Set(key, pv)
+			}
+		default:
+			elem := f.node
+			if elem.kind == "optional" {
+				elem = elem.elem
+			}
+			pv, err := protoFieldValue(func() protoreflect.Value { return m.NewField(fd) }, fd, elem, v)
+			if err != nil {
+				return err
+			}
+			m.Set(fd, pv)
+		}
+	}
+	return nil
+}
+
+func protoFieldValue(newValue func() protoreflect.Value, fd protoreflect.FieldDescriptor, n *schemaNode, v interface{}) (protoreflect.Value, error) {
+	switch n.kind {
+	case "record":
+		pv := newValue()
+		return pv, protoMessage(pv.Message(), n, v.(map[string]interface{}))
+	case "enum":
+		ev := fd.Enum().Values().ByName(protoreflect.Name(v.(string)))
+		if ev == nil {
+			return protoreflect.Value{}, fmt.Errorf("%s: unknown enum value %v", fd.FullName(), v)
+		}
+		return protoreflect.ValueOfEnum(ev.Number()), nil
+	}
+	return protoreflect.ValueOf(v), nil
+}
+
+func protoMapKey(fd protoreflect.FieldDescriptor, kind, k string) (protoreflect.MapKey, error) {
+	if kind == "string" {
+		return protoreflect.ValueOfString(k).MapKey(), nil
+	}
+	if kind == "bool" {
+		b, err := strconv.ParseBool(k)
+		return protoreflect.ValueOfBool(b).MapKey(), err
+	}
+	v, err := coerceScalar(kind, json.Number(k))
+	if err != nil {
+		return protoreflect.MapKey{}, fmt.Errorf("%s key: %v", fd.FullName(), err)
+	}
+	return protoreflect.ValueOf(v).MapKey(), nil
+}
+
+// avroValue turns a tree coerced to an Avro node into what hamba/avro
+// encodes generically: every record field present, null for absent
+// optional values and arrays for fixed.
+func avroValue(n *schemaNode, v interface{}) interface{} {
+	switch n.kind {
+	case "record":
+		m := v.(map[string]interface{})
+		out := make(map[string]interface{}, len(n.fields))
+		for _, f := range n.fields {
+			out[f.name] = nil
+			if fv, ok := m[f.name]; ok {
+				out[f.name] = avroValue(f.node, fv)
+			}
+		}
+		return out
+	case "list":
+		items := v.([]interface{})
+		out := make([]interface{}, len(items))
+		for i, item := range items {
+			out[i] = avroValue(n.elem, item)
+		}
+		return out
+	case "map":
+		m := v.(map[string]interface{})
+		out := make(map[string]interface{}, len(m))
+		for k, item := range m {
+			out[k] = avroValue(n.elem, item)
+		}
+		return out
+	case "optional":
+		return avroValue(n.elem, v)
+	case "bytes":
+		if n.size > 0 {
+			arr := reflect.New(reflect.ArrayOf(n.size, reflect.TypeOf(byte(0)))).Elem()
+			reflect.Copy(arr, reflect.ValueOf(v))
+			return arr.Interface()
+		}
+	}
+	return v
+}
+
+// schemaSource is what -schema-proto and -schema-avro loaded. Values are
+// generated from root, the .proto message when both are given.
+type schemaSource struct {
+	name     string
+	root     *schemaNode
+	proto    protoreflect.MessageDescriptor
+	protoN   *schemaNode
+	avro     avro.Schema
+	avroNode *schemaNode
+}
+
+func loadSchemaSource(protoPath, message, avroPath string) (*schemaSource, error) {
+	src := &schemaSource{}
+	var names []string
+	if protoPath != "" {
+		md, err := compileProtoMessage(protoPath, message)
+		if err != nil {
+			return nil, err
+		}
+		src.proto = md
+		src.protoN = protoNode(md, make(map[protoreflect.FullName]*schemaNode))
+		names = append(names, protoPath+":"+string(md.FullName()))
+	}
+	if avroPath != "" {
+		text, err := ioutil.ReadFile(avroPath)
+		if err != nil {
+			return nil, err
+		}
+		if src.avro, err = avro.ParseWithCache(string(text), "", &avro.SchemaCache{}); err != nil {
+			return nil, fmt.Errorf("%s: %v", avroPath, err)
+		}
+		if src.avroNode, err = avroNode(src.avro, make(map[string]*schemaNode)); err != nil {
+			return nil, fmt.Errorf("%s: %v", avroPath, err)
+		}
+		names = append(names, avroPath)
+	}
+	src.root = src.protoN
+	if src.root == nil {
+		src.root = src.avroNode
+	}
+	if src.root.kind != "record" {
+		return nil, fmt.Errorf("the schema must be a record")
+	}
+	src.name = strings.Join(names, ", ")
+	return src, nil
+}
+
+// generate draws n values, all generated up front like generateCorpus.
+func (src *schemaSource) generate(seed int64, shape Shape, n int) ([]map[string]interface{}, error) {
+	g := &schemaGen{rnd: rand.New(rand.NewSource(seed)), shape: shape, depth: make(map[*schemaNode]int)}
+	values := make([]map[string]interface{}, n)
+	for i := range values {
+		v, ok := g.value(src.root)
+		if !ok {
+			return nil, fmt.Errorf("%s nests itself without an optional or repeated field", src.root.name)
+		}
+		values[i] = v.(map[string]interface{})
+	}
+	return values, nil
+}
+
+// schemaCodec runs one format over generic values: maps, slices and
+// scalars for the self-describing formats, dynamicpb and generic Avro
+// for the schema based ones. It is a Codec over what prepare returns, so
+// serialise times it like any other; prepare and tree, which convert to
+// and from the generated trees, stay out of the timings.
+type schemaCodec struct {
+	name        string
+	base64Bytes bool
+	prepare     func(tree map[string]interface{}) (interface{}, error)
+	marshal     func(v interface{}) ([]byte, error)
+	newValue    func() interface{}
+	unmarshal   func(data []byte, v interface{}) error
+	tree        func(v interface{}) interface{}
+}
+
+func (sc *schemaCodec) Name() string                               { return sc.name }
+func (sc *schemaCodec) Marshal(v interface{}) ([]byte, error)      { return sc.marshal(v) }
+func (sc *schemaCodec) Unmarshal(data []byte, v interface{}) error { return sc.unmarshal(data, v) }
+func (sc *schemaCodec) NewModel() interface{}                      { return sc.newValue() }
+
+// schemaCodecFor adapts a registered codec, or returns nil when it only
+// works on its generated types (XML cannot encode maps at all).
+func schemaCodecFor(c Codec, src *schemaSource) *schemaCodec {
+	sc := &schemaCodec{
+		name: c.Name(),
+		prepare: func(tree map[string]interface{}) (interface{}, error) {
+			return tree, nil
+		},
+		marshal:   c.Marshal,
+		newValue:  func() interface{} { return new(interface{}) },
+		unmarshal: c.Unmarshal,
+		tree: func(v interface{}) interface{} {
+			return reflect.ValueOf(v).Elem().Interface()
+		},
+	}
+	switch c.(type) {
+	case gobCodec, bsonCodec:
+		sc.newValue = func() interface{} { return new(map[string]interface{}) }
+	case jsonCodec:
+		// numbers stay exact, as a schema aware reader would keep them
+		sc.base64Bytes = true
+		sc.unmarshal = func(data []byte, v interface{}) error {
+			d := json.NewDecoder(bytes.NewReader(data))
+			d.UseNumber()
+			return d.Decode(v)
+		}
+	case yamlCodec, msgpCodec, cborCodec:
+	case *protoCodec:
+		if src.proto == nil {
+			return nil
+		}
+		sc.prepare = func(tree map[string]interface{}) (interface{}, error) {
+			v, err := coerce(src.protoN, tree, false)
+			if err != nil {
+				return nil, err
+			}
+			m := dynamicpb.NewMessage(src.proto)
+			if err := protoMessage(m, src.protoN, v.(map[string]interface{})); err != nil {
+				return nil, err
+			}
+			return m, nil
+		}
+		sc.marshal = func(v interface{}) ([]byte, error) {
+			return proto.Marshal(v.(proto.Message))
+		}
+		sc.newValue = func() interface{} { return dynamicpb.NewMessage(src.proto) }
+		sc.unmarshal = func(data []byte, v interface{}) error {
+			return proto.Unmarshal(data, v.(proto.Message))
+		}
+		sc.tree = func(v interface{}) interface{} {
+			return protoTree(v.(*dynamicpb.Message))
+		}
+	case *avroCodec:
+		if src.avro == nil {
+			return nil
+		}
+		sc.prepare = func(tree map[string]interface{}) (interface{}, error) {
+			v, err := coerce(src.avroNode, tree, false)
+			if err != nil {
+				return nil, err
+			}
+			return avroValue(src.avroNode, v), nil
+		}
+		sc.marshal = func(v interface{}) ([]byte, error) {
+			return avro.Marshal(src.avro, v)
+		}
+		sc.unmarshal = func(data []byte, v interface{}) error {
+			return avro.Unmarshal(src.avro, data, v)
+		}
+		sc.tree = func(v interface{}) interface{} {
+			return avroTree(src.avro, *v.(*interface{}))
+		}
+	default:
+		return nil
+	}
+	return sc
+}
+
+// check compares a decoded tree with want in canonical JSON.
+func (sc *schemaCodec) check(root *schemaNode, want map[string]interface{}, decoded interface{}) error {
+	got, err := coerce(root, decoded, sc.base64Bytes)
+	if err != nil {
+		return err
+	}
+	w, err := canonicalJSON(want)
+	if err != nil {
+		return err
+	}
+	g, err := canonicalJSON(got)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(w, g) {
+		return fmt.Errorf("decoded value differs from the generated one on line %d of its canonical JSON", firstDiffLine(w, g))
+	}
+	return nil
+}
+
+// runSchema benchmarks the formats that can carry generic values over
+// n values generated from the user schema and fills report like the
+// regular benchmark. It returns the process exit code.
+func runSchema(enabled []Codec, src *schemaSource, shape Shape, seed int64, n, runs, warmup int, report *Report) int {
+	values, err := src.generate(seed, shape, n)
+	if err != nil {
+		fmt.Println(err)
+		return 2
+	}
+
+	var scs []*schemaCodec
+	report.Config.Formats = report.Config.Formats[:0]
+	for _, c := range enabled {
+		if !ownWireFormat(c) {
+			continue
+		}
+		if sc := schemaCodecFor(c, src); sc != nil {
+			scs = append(scs, sc)
+			report.Config.Formats = append(report.Config.Formats, sc.name)
+		} else {
+			fmt.Printf("%s: skipped, it needs generated types or a schema of its own\n", c.Name())
+		}
+	}
+	fmt.Println()
+
+	code := 0
+	for _, sc := range scs {
+		overall := newTotals(nil)
+		failures := 0
+		for j, tree := range values {
+			v, err := sc.prepare(tree)
+			sum := newTotals(nil)
+			if err == nil {
+				for i := 0; i < warmup; i++ {
+					serialise(sc, v, nil, false, true)
+				}
+				var last runResult
+				for i := 0; i < runs; i++ {
+					if last = serialise(sc, v, nil, false, true); last.err != nil {
+						break
+					}
+					sum.add(last)
+				}
+				sum.size = last.size
+				sum.measurePeaks(sc, v)
+				switch {
+				case last.err != nil:
+					err = last.err
+				case last.decoded == nil:
+					err = fmt.Errorf("nothing was decoded, so nothing could be checked")
+				default:
+					err = sc.check(src.root, tree, sc.tree(last.decoded))
+				}
+			}
+			if err != nil {
+				failures++
+				if failures <= maxDiffs {
+					fmt.Printf("%s FIDELITY FAILURE on test #%d: %v\n", sc.name, j, err)
+				}
+			}
+			overall.merge(sum)
+			report.Results = append(report.Results, sum.result(j, sc.name, false, err == nil))
+		}
+
+		fmt.Printf("Overall %s\n Sum size: %d\n", sc.name, overall.size)
+		overall.print(false)
+		report.Overall = append(report.Overall, overall.result(-1, sc.name, false, failures == 0))
+		if failures > 0 {
+			fmt.Printf("Fidelity %s: FAILED on %d of %d tests\n\n", sc.name, failures, n)
+			code = 1
+		} else {
+			fmt.Printf("Fidelity %s: OK\n\n", sc.name)
+		}
+	}
+	return code
+}
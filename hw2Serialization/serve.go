@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// server runs benchmarks on payloads posted over HTTP. Benchmarks run one
+// at a time, since timings and memory statistics are process wide.
+type server struct {
+	mu       sync.Mutex
+	maxRuns  int
+	maxWork  int
+	maxBody  int64
+	warmup   int
+	runs     int
+	tol      float64
+	registry *prometheus.Registry
+
+	benchmarks *prometheus.CounterVec
+	size       *prometheus.GaugeVec
+	encode     *prometheus.HistogramVec
+	decode     *prometheus.HistogramVec
+	failures   *prometheus.CounterVec
+}
+
+func newServer() *server {
+	// 100ns to about 7s, a factor of 4 apart
+	buckets := prometheus.ExponentialBuckets(1e-7, 4, 14)
+	s := &server{
+		registry: prometheus.NewRegistry(),
+		benchmarks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "serbench_benchmarks_total",
+			Help: "Benchmark requests by outcome (ok, bad_request, failed).",
+		}, []string{"outcome"}),
+		size: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "serbench_encoded_bytes",
+			Help: "Encoded size of the last benchmarked payload.",
+		}, []string{"codec"}),
+		encode: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "serbench_encode_seconds",
+			Help:    "Time of every measured marshal.",
+			Buckets: buckets,
+		}, []string{"codec"}),
+		decode: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "serbench_decode_seconds",
+			Help:    "Time of every measured unmarshal.",
+			Buckets: buckets,
+		}, []string{"codec"}),
+		failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "serbench_fidelity_failures_total",
+			Help: "Payloads a codec did not decode back to what it encoded.",
+		}, []string{"codec"}),
+	}
+	s.registry.MustRegister(s.benchmarks, s.size, s.encode, s.decode, s.failures,
+		collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	return s
+}
+
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/benchmark", s.handleBenchmark)
+	mux.HandleFunc("/formats", s.handleFormats)
+	mux.Handle("/metrics", promhttp.HandlerFor(s.registry, promhttp.HandlerOpts{}))
+	return mux
+}
+
+func (s *server) handleFormats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(codecNames())
+}
+
+// handleBenchmark takes one Test or an array of them as JSON, in the
+// same form as the tests of a -save-corpus file, and answers with the
+// report of benchmarking them. The query may set runs, warmup, formats
+// and report (json, csv, markdown or html).
+func (s *server) handleBenchmark(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "POST a Test or an array of them as JSON", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	bad := func(format string, args ...interface{}) {
+		s.benchmarks.WithLabelValues("bad_request").Inc()
+		http.Error(w, fmt.Sprintf(format, args...), http.StatusBadRequest)
+	}
+
+	runs, warmup := s.runs, s.warmup
+	if v := q.Get("runs"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > s.maxRuns {
+			bad("runs must be a number from 1 to %d", s.maxRuns)
+			return
+		}
+		runs = n
+	}
+	if v := q.Get("warmup"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > s.maxRuns {
+			bad("warmup must be a number from 0 to %d", s.maxRuns)
+			return
+		}
+		warmup = n
+	}
+	enabled, err := selectCodecs(q.Get("formats"))
+	if err != nil {
+		bad("%v", err)
+		return
+	}
+	format := q.Get("report")
+	if format == "" {
+		format = "json"
+	}
+	if err := checkReportFormat(format); err != nil {
+		bad("%v", err)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, s.maxBody))
+	if err != nil {
+		bad("%v", err)
+		return
+	}
+	tests, err := parseTests(body)
+	if err != nil {
+		bad("payload: %v", err)
+		return
+	}
+
+	if work := (runs + warmup) * len(tests) * len(enabled); work > s.maxWork {
+		bad("(runs + warmup) x tests x formats is %d, at most %d round trips are allowed", work, s.maxWork)
+		return
+	}
+
+	report := newReport(ReportConfig{Runs: runs, Warmup: warmup, Tests: len(tests), Payload: "request"})
+	for _, c := range enabled {
+		report.Config.Formats = append(report.Config.Formats, c.Name())
+	}
+	failed := s.benchmark(enabled, tests, runs, warmup, report)
+
+	var out bytes.Buffer
+	if err := renderReport(&out, report, format); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if failed {
+		s.benchmarks.WithLabelValues("failed").Inc()
+	} else {
+		s.benchmarks.WithLabelValues("ok").Inc()
+	}
+	switch format {
+	case "json":
+		w.Header().Set("Content-Type", "application/json")
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	case "html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	default:
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	}
+	w.Write(out.Bytes())
+}
+
+// parseTests accepts a single Test object or an array of them.
+func parseTests(body []byte) ([]Test, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return nil, fmt.Errorf("empty body")
+	}
+	if body[0] == '[' {
+		var tests []Test
+		if err := json.Unmarshal(body, &tests); err != nil {
+			return nil, err
+		}
+		if len(tests) == 0 {
+			return nil, fmt.Errorf("no tests")
+		}
+		return tests, nil
+	}
+	var t Test
+	if err := json.Unmarshal(body, &t); err != nil {
+		return nil, err
+	}
+	return []Test{t}, nil
+}
+
+// benchmark runs one benchmark at a time; the deferred unlock keeps the
+// server usable after a codec panics, which net/http recovers from.
+func (s *server) benchmark(enabled []Codec, tests []Test, runs, warmup int, report *Report) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.run(enabled, tests, runs, warmup, report)
+}
+
+// run benchmarks tests like the regular mode, filling report and the
+// metrics, and tells whether any codec failed the round trip.
+func (s *server) run(enabled []Codec, tests []Test, runs, warmup int, report *Report) bool {
+	failed := false
+	overall := make(map[string]*totals)
+	failures := make(map[string]int)
+	for _, c := range enabled {
+		overall[c.Name()] = newTotals(nil)
+	}
+
+	for j, t := range tests {
+		for _, c := range enabled {
+			v := modelOf(c, t)
+			for i := 0; i < warmup; i++ {
+				serialise(c, v, nil, false, true)
+			}
+
+			sum := newTotals(nil)
+			var last runResult
+			for i := 0; i < runs; i++ {
+				last = serialise(c, v, nil, false, true)
+				sum.add(last)
+				s.encode.WithLabelValues(c.Name()).Observe(last.encode.Seconds())
+				s.decode.WithLabelValues(c.Name()).Observe(last.decode.Seconds())
+			}
+			sum.size = last.size
+			sum.measurePeaks(c, v)
+			s.size.WithLabelValues(c.Name()).Set(float64(last.size))
+
+			fidelityOK := len(checkRun(c, t, last, s.tol)) == 0
+			if !fidelityOK {
+				failed = true
+				failures[c.Name()]++
+				s.failures.WithLabelValues(c.Name()).Inc()
+			}
+			overall[c.Name()].merge(sum)
+			report.Results = append(report.Results, sum.result(j, c.Name(), false, fidelityOK))
+		}
+	}
+	for _, c := range enabled {
+		report.Overall = append(report.Overall, overall[c.Name()].result(-1, c.Name(), false, failures[c.Name()] == 0))
+	}
+	return failed
+}
+
+func serveCmd(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "address to listen on")
+	s := newServer()
+	fs.IntVar(&s.runs, "runs", 10, "default number of measured runs per payload and format")
+	fs.IntVar(&s.warmup, "warmup", 3, "default number of unmeasured warm-up runs")
+	fs.IntVar(&s.maxRuns, "max-runs", 1000, "largest runs or warmup a request may ask for")
+	fs.IntVar(&s.maxWork, "max-work", 100000, "largest (runs + warmup) x tests x formats a request may ask for")
+	fs.Int64Var(&s.maxBody, "max-body", 8<<20, "largest accepted payload in bytes")
+	fs.Float64Var(&s.tol, "tolerance", 1e-6, "relative tolerance for float comparison")
+	fs.BoolVar(&measurePeakHeap, "peak-heap", true, "measure the heap high-water mark of one extra encode and decode per payload and format")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: main serve [flags]")
+		fmt.Fprintln(fs.Output(), "POST /benchmark?runs=&warmup=&formats=&report= with a Test or an array of Tests as JSON;")
+		fmt.Fprintln(fs.Output(), "GET /formats lists the formats, GET /metrics serves Prometheus metrics")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	// the defaults obey the same limits as the query parameters they stand for
+	if s.runs < 1 || s.runs > s.maxRuns || s.warmup < 0 || s.warmup > s.maxRuns {
+		fmt.Printf("-runs must be from 1 and -warmup from 0 to -max-runs (%d)\n", s.maxRuns)
+		return 2
+	}
+
+	for _, c := range codecs {
+		if st, ok := c.(Setuper); ok {
+			if err := st.Setup(); err != nil {
+				fmt.Println(c.Name(), "setup error:", err)
+				return 1
+			}
+		}
+	}
+
+	log.Printf("serving on %s", *addr)
+	if err := http.ListenAndServe(*addr, s.routes()); err != nil {
+		log.Println(err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+)
+
+// sizeField is a Test field the size breakdown attributes bytes to.
+type sizeField struct {
+	name string
+	zero func(t *Test)
+	raw  func(t Test) int // bytes of the data itself, without any encoding
+}
+
+var sizeFields = []sizeField{
+	{"ID", func(t *Test) { t.ID = 0 }, func(t Test) int { return 4 }},
+	{"Name", func(t *Test) { t.Name = "" }, func(t Test) int { return len(t.Name) }},
+	{"SomeNumericArray", func(t *Test) { t.SomeNumericArray = nil }, func(t Test) int { return 4 * len(t.SomeNumericArray) }},
+	{"SomeFloatArray", func(t *Test) { t.SomeFloatArray = nil }, func(t Test) int { return 4 * len(t.SomeFloatArray) }},
+	{"Tests", func(t *Test) { t.Tests = nil }, func(t Test) int { return rawTestStructs(t.Tests) }},
+	{"Comment", func(t *Test) { t.Comment = nil }, func(t Test) int {
+		if t.Comment == nil {
+			return 0
+		}
+		return len(*t.Comment)
+	}},
+	{"Labels", func(t *Test) { t.Labels = nil }, func(t Test) int {
+		n := 0
+		for k, v := range t.Labels {
+			n += len(k) + len(v)
+		}
+		return n
+	}},
+	{"Blob", func(t *Test) { t.Blob = nil }, func(t Test) int { return len(t.Blob) }},
+}
+
+func rawTestStructs(ts []TestStruct) int {
+	n := 0
+	for _, s := range ts {
+		n += len(s.Some) + len(s.Other) + rawTestStructs(s.Children)
+	}
+	return n
+}
+
+// FieldSize is what one field costs in a format: Raw is the size of its
+// data in memory (4 bytes per int32 and float32, string and byte lengths)
+// and Encoded how much smaller the output gets when the field is zeroed.
+// Encoded below Raw means the format compresses the value (varints,
+// short strings), above it the rest is the format's own overhead.
+type FieldSize struct {
+	Field   string `json:"field"`
+	Raw     int    `json:"raw"`
+	Encoded int    `json:"encoded"`
+}
+
+// SizeBreakdown attributes the encoded bytes of one codec, summed over
+// all tests, to the fields of Test. Empty is the size of a Test with every
+// field zeroed; Other is what is left, mostly length prefixes that shrink
+// together with their field. Notes measure overhead specific to the
+// format in the first test.
+type SizeBreakdown struct {
+	Codec  string      `json:"codec"`
+	Total  int         `json:"total"`
+	Empty  int         `json:"empty"`
+	Other  int         `json:"other"`
+	Fields []FieldSize `json:"fields"`
+	Notes  []string    `json:"notes,omitempty"`
+}
+
+// sizeOf encodes t with c.
+func sizeOf(c Codec, t Test) (int, []byte, error) {
+	data, err := c.Marshal(modelOf(c, t))
+	if err != nil {
+		return 0, nil, err
+	}
+	return len(data), data, nil
+}
+
+// breakdown fails when c cannot encode a test or one of its zeroed
+// variants, as a missing size would be counted as a field's bytes.
+func breakdown(c Codec, tests []Test) (SizeBreakdown, error) {
+	b := SizeBreakdown{Codec: c.Name(), Fields: make([]FieldSize, len(sizeFields))}
+	empty, _, err := sizeOf(c, Test{})
+	if err != nil {
+		return b, fmt.Errorf("empty Test: %v", err)
+	}
+	var sample []byte
+	for j, t := range tests {
+		total, data, err := sizeOf(c, t)
+		if err != nil {
+			return b, fmt.Errorf("test #%d: %v", j, err)
+		}
+		if sample == nil {
+			sample = data
+		}
+		b.Total += total
+		b.Empty += empty
+		b.Other += total - empty
+		for i, f := range sizeFields {
+			zeroed := t
+			f.zero(&zeroed)
+			size, _, err := sizeOf(c, zeroed)
+			if err != nil {
+				return b, fmt.Errorf("test #%d without %s: %v", j, f.name, err)
+			}
+			b.Fields[i].Field = f.name
+			b.Fields[i].Raw += f.raw(t)
+			b.Fields[i].Encoded += total - size
+			b.Other -= total - size
+		}
+	}
+	b.Notes = overheadNotes(c, tests[0], sample)
+	return b, nil
+}
+
+// overheadNotes measures on the first test what the format spends on
+// itself rather than on the data.
+func overheadNotes(c Codec, t Test, data []byte) []string {
+	var notes []string
+	note := func(what string, n int) {
+		notes = append(notes, fmt.Sprintf("%s: %d bytes (%.1f%%)", what, n, 100*float64(n)/float64(len(data))))
+	}
+
+	switch c.(type) {
+	case gobCodec:
+		// a gob stream describes every type once, before the first value
+		var buf bytes.Buffer
+		enc := gob.NewEncoder(&buf)
+		if enc.Encode(t) == nil {
+			first := buf.Len()
+			if enc.Encode(t) == nil {
+				note("type descriptors sent before the first value", 2*first-buf.Len())
+			}
+		}
+	case xmlCodec:
+		note("markup (tags and attributes)", markupBytes(data))
+	case yamlCodec:
+		indent, markers := yamlLayout(data)
+		note("indentation", indent)
+		note("list markers \"- \"", markers)
+		note("keys with their colons", yamlKeyBytes(data))
+	case jsonCodec, jsonEngine, easyjsonCodec:
+		note("keys with their quotes and colons", jsonKeyBytes(data))
+		note("other punctuation ({}[],)", countBytes(data, "{}[],"))
+	case bsonCodec:
+		note("field names (BSON repeats them in every document, arrays use \"0\", \"1\", ... as names)", bsonNameBytes(data))
+	}
+	return notes
+}
+
+// markupBytes counts the bytes inside <...>.
+func markupBytes(data []byte) int {
+	n, in := 0, false
+	for _, b := range data {
+		if b == '<' {
+			in = true
+		}
+		if in {
+			n++
+		}
+		if b == '>' {
+			in = false
+		}
+	}
+	return n
+}
+
+// yamlLayout counts leading spaces and "- " list markers.
+func yamlLayout(data []byte) (indent, markers int) {
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		for {
+			trimmed := bytes.TrimLeft(line, " ")
+			indent += len(line) - len(trimmed)
+			if !bytes.HasPrefix(trimmed, []byte("- ")) {
+				break
+			}
+			markers += 2
+			line = trimmed[2:]
+		}
+	}
+	return indent, markers
+}
+
+// jsonKeyBytes counts the object keys of compact JSON with their quotes
+// and colons.
+func jsonKeyBytes(data []byte) int {
+	n := 0
+	for i := 0; i < len(data); i++ {
+		if data[i] != '"' {
+			continue
+		}
+		start := i
+		for i++; i < len(data) && data[i] != '"'; i++ {
+			if data[i] == '\\' {
+				i++
+			}
+		}
+		if i+1 < len(data) && data[i+1] == ':' {
+			n += i - start + 2
+		}
+	}
+	return n
+}
+
+// yamlKeyBytes counts the mapping keys of block YAML with their colons.
+func yamlKeyBytes(data []byte) int {
+	n := 0
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimLeft(line, " ")
+		for bytes.HasPrefix(line, []byte("- ")) {
+			line = bytes.TrimLeft(line[2:], " ")
+		}
+		if i := bytes.Index(line, []byte(": ")); i > 0 && line[0] != '"' && line[0] != '\'' {
+			n += i + 1
+		} else if bytes.HasSuffix(line, []byte(":")) {
+			n += len(line)
+		}
+	}
+	return n
+}
+
+func countBytes(data []byte, set string) int {
+	n := 0
+	for _, b := range data {
+		if bytes.IndexByte([]byte(set), b) >= 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// bsonNameBytes walks a BSON document and counts the element names with
+// their terminating zero bytes.
+func bsonNameBytes(doc []byte) int {
+	if len(doc) < 5 {
+		return 0
+	}
+	n := 0
+	for i := 4; i < len(doc)-1; {
+		kind := doc[i]
+		end := bytes.IndexByte(doc[i+1:], 0)
+		if end < 0 {
+			return n
+		}
+		n += end + 1
+		i += 1 + end + 1
+		size := bsonValueSize(kind, doc[i:])
+		if size < 0 || i+size > len(doc) {
+			return n
+		}
+		if kind == 0x03 || kind == 0x04 {
+			n += bsonNameBytes(doc[i : i+size])
+		}
+		i += size
+	}
+	return n
+}
+
+func bsonValueSize(kind byte, b []byte) int {
+	length := -1
+	if len(b) >= 4 {
+		length = int(binary.LittleEndian.Uint32(b))
+	}
+	switch kind {
+	case 0x01, 0x09, 0x11, 0x12: // double, datetime, timestamp, int64
+		return 8
+	case 0x02: // string
+		if length < 0 {
+			return -1
+		}
+		return 4 + length
+	case 0x03, 0x04: // document, array
+		return length
+	case 0x05: // binary
+		if length < 0 {
+			return -1
+		}
+		return 5 + length
+	case 0x08: // bool
+		return 1
+	case 0x0A: // null
+		return 0
+	case 0x10: // int32
+		return 4
+	}
+	return -1
+}
+
+// runSizes prints and reports the size breakdown of every enabled codec.
+// A codec that cannot encode the tests is left out of the report.
+func runSizes(enabled []Codec, tests []Test, report *Report) {
+	for _, c := range enabled {
+		b, err := breakdown(c, tests)
+		if err != nil {
+			fmt.Printf("%s: skipped, cannot encode %v\n\n", c.Name(), err)
+			continue
+		}
+		report.Sizes = append(report.Sizes, b)
+
+		fmt.Printf("%s: %d bytes, %d for an empty Test\n", b.Codec, b.Total, b.Empty)
+		fmt.Printf("  %-18s %8s %8s %9s %7s\n", "field", "raw", "encoded", "overhead", "share")
+		for _, f := range b.Fields {
+			fmt.Printf("  %-18s %8d %8d %+9d %6.1f%%\n", f.Field, f.Raw, f.Encoded, f.Encoded-f.Raw, share(f.Encoded, b.Total))
+		}
+		fmt.Printf("  %-18s %8s %8d %9s %6.1f%%\n", "empty Test", "", b.Empty, "", share(b.Empty, b.Total))
+		fmt.Printf("  %-18s %8s %8d %9s %6.1f%%\n", "other", "", b.Other, "", share(b.Other, b.Total))
+		if len(b.Notes) > 0 {
+			fmt.Println("  in the first test:")
+		}
+		for _, n := range b.Notes {
+			fmt.Println("    " + n)
+		}
+		fmt.Println()
+	}
+}
+
+func share(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return 100 * float64(n) / float64(total)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestComputeStats(t *testing.T) {
+	seq := make([]time.Duration, 100)
+	for i := range seq {
+		seq[i] = time.Duration(100 - i)
+	}
+	cases := []struct {
+		name    string
+		samples []time.Duration
+		want    Stats
+	}{
+		{"empty", nil, Stats{}},
+		{"single", []time.Duration{7}, Stats{N: 1, Min: 7, Max: 7, Mean: 7, Median: 7, P95: 7, P99: 7}},
+		// the textbook set with mean 5 and population standard deviation 2
+		{"unsorted", []time.Duration{9, 4, 2, 5, 4, 7, 4, 5}, Stats{N: 8, Min: 2, Max: 9, Mean: 5, Median: 4, P95: 9, P99: 9, StdDev: 2}},
+		// 1..100 in reverse: mean 50.5 and standard deviation sqrt(9999/12),
+		// both truncated to whole nanoseconds, nearest-rank percentiles
+		{"1 to 100", seq, Stats{N: 100, Min: 1, Max: 100, Mean: 50, Median: 50, P95: 95, P99: 99, StdDev: 28}},
+	}
+	for _, c := range cases {
+		before := append([]time.Duration(nil), c.samples...)
+		if got := computeStats(c.samples); got != c.want {
+			t.Errorf("%s: got %+v, want %+v", c.name, got, c.want)
+		}
+		for i := range before {
+			if c.samples[i] != before[i] {
+				t.Errorf("%s: computeStats reordered its input", c.name)
+				break
+			}
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := []time.Duration{15, 20, 35, 40, 50}
+	cases := []struct {
+		p    float64
+		want time.Duration
+	}{
+		{0, 15}, {5, 15}, {30, 20}, {40, 20}, {50, 35}, {100, 50},
+	}
+	for _, c := range cases {
+		if got := percentile(sorted, c.p); got != c.want {
+			t.Errorf("percentile %v: got %v, want %v", c.p, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bufio"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/hamba/avro/v2/ocf"
+	"github.com/vmihailenco/msgpack"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/proto"
+)
+
+// Streamer is implemented by codecs that can write and read a sequence
+// of records through io.Writer/io.Reader, used by -stream.
+type Streamer interface {
+	NewEncoder(w io.Writer) (StreamEncoder, error)
+	NewDecoder(r io.Reader) (StreamDecoder, error)
+}
+
+// StreamEncoder writes one record per Encode; Close flushes whatever
+// the format still buffers, but does not close the underlying writer.
+type StreamEncoder interface {
+	Encode(v interface{}) error
+	Close() error
+}
+
+// StreamDecoder reads one record per Decode and returns io.EOF after the last one.
+type StreamDecoder interface {
+	Decode(v interface{}) error
+}
+
+// encoderFunc adapts the standard Encoder.Encode methods, which need no Close.
+type encoderFunc func(v interface{}) error
+
+func (f encoderFunc) Encode(v interface{}) error { return f(v) }
+func (f encoderFunc) Close() error               { return nil }
+
+// decoderFunc adapts a decode function to StreamDecoder.
+type decoderFunc func(v interface{}) error
+
+func (f decoderFunc) Decode(v interface{}) error { return f(v) }
+
+func (gobCodec) NewEncoder(w io.Writer) (StreamEncoder, error) {
+	return encoderFunc(gob.NewEncoder(w).Encode), nil
+}
+
+func (gobCodec) NewDecoder(r io.Reader) (StreamDecoder, error) {
+	return gob.NewDecoder(r), nil
+}
+
+// Json streams are newline separated documents, as written by json.Encoder.
+func (jsonCodec) NewEncoder(w io.Writer) (StreamEncoder, error) {
+	return encoderFunc(json.NewEncoder(w).Encode), nil
+}
+
+func (jsonCodec) NewDecoder(r io.Reader) (StreamDecoder, error) {
+	return json.NewDecoder(r), nil
+}
+
+// Proto streams prefix every message with its varint encoded length.
+func (*protoCodec) NewEncoder(w io.Writer) (StreamEncoder, error) {
+	return encoderFunc(func(v interface{}) error {
+		m, ok := v.(proto.Message)
+		if !ok {
+			return fmt.Errorf("proto: %T is not a proto.Message", v)
+		}
+		_, err := protodelim.MarshalTo(w, m)
+		return err
+	}), nil
+}
+
+func (*protoCodec) NewDecoder(r io.Reader) (StreamDecoder, error) {
+	br, ok := r.(protodelim.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return decoderFunc(func(v interface{}) error {
+		m, ok := v.(proto.Message)
+		if !ok {
+			return fmt.Errorf("proto: %T is not a proto.Message", v)
+		}
+		return protodelim.UnmarshalFrom(br, m)
+	}), nil
+}
+
+// Avro streams are object container files carrying the schema in their header.
+func (c *avroCodec) NewEncoder(w io.Writer) (StreamEncoder, error) {
+	return ocf.NewEncoderWithSchema(c.schema, w)
+}
+
+func (*avroCodec) NewDecoder(r io.Reader) (StreamDecoder, error) {
+	dec, err := ocf.NewDecoder(r)
+	if err != nil {
+		return nil, err
+	}
+	return decoderFunc(func(v interface{}) error {
+		if !dec.HasNext() {
+			if err := dec.Error(); err != nil {
+				return err
+			}
+			return io.EOF
+		}
+		return dec.Decode(v)
+	}), nil
+}
+
+func (msgpCodec) NewEncoder(w io.Writer) (StreamEncoder, error) {
+	return encoderFunc(msgpack.NewEncoder(w).Encode), nil
+}
+
+func (msgpCodec) NewDecoder(r io.Reader) (StreamDecoder, error) {
+	return msgpack.NewDecoder(r), nil
+}
+
+func (cborCodec) NewEncoder(w io.Writer) (StreamEncoder, error) {
+	return encoderFunc(cbor.NewEncoder(w).Encode), nil
+}
+
+func (cborCodec) NewDecoder(r io.Reader) (StreamDecoder, error) {
+	return cbor.NewDecoder(r), nil
+}
+
+// streamSampleEvery is how often the heap is sampled while streaming;
+// the sampler runs next to the timed pass, so it must stay cheap.
+const streamSampleEvery = 100 * time.Microsecond
+
+// StreamResult is the outcome of one codec in -stream mode.
+type StreamResult struct {
+	Codec    string     `json:"codec"`
+	Records  int        `json:"records"`
+	Bytes    int64      `json:"bytes"`
+	Encode   StreamPass `json:"encode"`
+	Decode   StreamPass `json:"decode"`
+	Failures int        `json:"failures"`
+}
+
+// StreamPass is the throughput of writing or reading a whole stream.
+// Time covers the codec together with the buffered file I/O under it
+// (bufio writes and the final flush, reads through bufio), but not
+// generating or verifying the records. PeakHeap is how far the heap rose
+// above its collected size during the pass, which also holds the one
+// record being generated or verified.
+type StreamPass struct {
+	Time          time.Duration `json:"time_ns"`
+	RecordsPerSec float64       `json:"records_per_sec"`
+	MBPerSec      float64       `json:"mb_per_sec"`
+	PeakHeap      uint64        `json:"peak_heap_bytes"`
+}
+
+func newStreamPass(d time.Duration, records int, bytes int64, peak uint64) StreamPass {
+	p := StreamPass{Time: d, PeakHeap: peak}
+	if sec := d.Seconds(); sec > 0 {
+		p.RecordsPerSec = float64(records) / sec
+		p.MBPerSec = float64(bytes) / 1e6 / sec
+	}
+	return p
+}
+
+func (p StreamPass) String() string {
+	return fmt.Sprintf("%v, %.0f records/s, %.2f MB/s, peak heap %d B", p.Time, p.RecordsPerSec, p.MBPerSec, p.PeakHeap)
+}
+
+// runStream writes n records of the given shape through files/<Name>.stream
+// and reads them back. Records are generated from seed one at a time and
+// regenerated for verification, so memory does not grow with n unless the
+// codec itself buffers the stream.
+func runStream(c Codec, s Streamer, shape Shape, seed int64, n int, verify bool, tol float64) (StreamResult, error) {
+	res := StreamResult{Codec: c.Name(), Records: n}
+	path := artifactPath(c) + ".stream"
+
+	f, err := os.Create(path)
+	if err != nil {
+		return res, err
+	}
+	bw := bufio.NewWriter(f)
+	enc, err := s.NewEncoder(bw)
+	if err != nil {
+		f.Close()
+		return res, err
+	}
+
+	peak := startHeapSampler(false, streamSampleEvery)
+	var elapsed time.Duration
+	rnd := rand.New(rand.NewSource(seed))
+	for i := 0; i < n; i++ {
+		v := modelOf(c, generateTest(rnd, shape))
+		start := time.Now()
+		err = enc.Encode(v)
+		elapsed += time.Since(start)
+		if err != nil {
+			peak.stop()
+			f.Close()
+			return res, fmt.Errorf("encoding record %d: %v", i, err)
+		}
+	}
+	start := time.Now()
+	if err = enc.Close(); err == nil {
+		err = bw.Flush()
+	}
+	elapsed += time.Since(start)
+	encodePeak := peak.stop()
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return res, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return res, err
+	}
+	res.Bytes = info.Size()
+	res.Encode = newStreamPass(elapsed, n, res.Bytes, encodePeak)
+
+	f, err = os.Open(path)
+	if err != nil {
+		return res, err
+	}
+	defer f.Close()
+	dec, err := s.NewDecoder(bufio.NewReader(f))
+	if err != nil {
+		return res, err
+	}
+
+	peak = startHeapSampler(false, streamSampleEvery)
+	elapsed = 0
+	rnd = rand.New(rand.NewSource(seed))
+	read := 0
+	for ; ; read++ {
+		v := newModel(c)
+		start := time.Now()
+		err := dec.Decode(v)
+		elapsed += time.Since(start)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			peak.stop()
+			return res, fmt.Errorf("decoding record %d: %v", read, err)
+		}
+		if !verify || read >= n {
+			continue
+		}
+		if diffs := verifyRoundTrip(c, generateTest(rnd, shape), v, tol); len(diffs) > 0 {
+			if res.Failures == 0 {
+				fmt.Printf("%s FIDELITY FAILURE on record #%d:\n", c.Name(), read)
+				for _, d := range diffs {
+					fmt.Println("  ", d)
+				}
+			}
+			res.Failures++
+		}
+	}
+	res.Decode = newStreamPass(elapsed, read, res.Bytes, peak.stop())
+
+	if read != n {
+		return res, fmt.Errorf("wrote %d records, read back %d", n, read)
+	}
+	return res, nil
+}
+
+// runStreams runs -stream over every enabled codec that implements
+// Streamer and returns the process exit code.
+func runStreams(enabled []Codec, shape Shape, seed int64, n int, verify bool, tol float64, report *Report) int {
+	code := 0
+	for _, c := range enabled {
+		s, ok := c.(Streamer)
+		if !ok {
+			fmt.Printf("%s: no streaming support, skipped\n", c.Name())
+			continue
+		}
+		res, err := runStream(c, s, shape, seed, n, verify, tol)
+		if err != nil {
+			fmt.Printf("%s stream error: %v\n", c.Name(), err)
+			code = 1
+			continue
+		}
+		if res.Failures > 0 {
+			code = 1
+		}
+		report.Streams = append(report.Streams, res)
+
+		fmt.Printf("%s stream: %d records, %d bytes\n", c.Name(), res.Records, res.Bytes)
+		fmt.Printf(" Encode: %v\n Decode: %v\n", res.Encode, res.Decode)
+		if verify {
+			if res.Failures > 0 {
+				fmt.Printf(" Fidelity: FAILED on %d of %d records\n", res.Failures, res.Records)
+			} else {
+				fmt.Println(" Fidelity: OK")
+			}
+		}
+		fmt.Println()
+	}
+	return code
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+)
+
+// maxDiffs limits how many field differences are reported per comparison.
+const maxDiffs = 10
+
+// testOf converts a value decoded by c back to Test.
+func testOf(c Codec, v interface{}) Test {
+	if m, ok := c.(Modeler); ok {
+		return m.Test(v)
+	}
+	return *v.(*Test)
+}
+
+// verifyRoundTrip compares what c decoded with the original input and
+// returns a field-level description of every difference. Floats are
+// compared with the relative tolerance tol; nil and empty slices are equal.
+func verifyRoundTrip(c Codec, want Test, decoded interface{}, tol float64) []string {
+	return diffTest(want, testOf(c, decoded), tol)
+}
+
+func diffTest(want, got Test, tol float64) []string {
+	var diffs []string
+	add := func(format string, args ...interface{}) {
+		if len(diffs) < maxDiffs {
+			diffs = append(diffs, fmt.Sprintf(format, args...))
+		} else if len(diffs) == maxDiffs {
+			diffs = append(diffs, "...")
+		}
+	}
+
+	if want.ID != got.ID {
+		add("ID: want %d, got %d", want.ID, got.ID)
+	}
+	if want.Name != got.Name {
+		add("Name: want %q, got %q", want.Name, got.Name)
+	}
+
+	if len(want.SomeNumericArray) != len(got.SomeNumericArray) {
+		add("SomeNumericArray: want %d elements, got %d", len(want.SomeNumericArray), len(got.SomeNumericArray))
+	} else {
+		for i := range want.SomeNumericArray {
+			if want.SomeNumericArray[i] != got.SomeNumericArray[i] {
+				add("SomeNumericArray[%d]: want %d, got %d", i, want.SomeNumericArray[i], got.SomeNumericArray[i])
+			}
+		}
+	}
+
+	if len(want.SomeFloatArray) != len(got.SomeFloatArray) {
+		add("SomeFloatArray: want %d elements, got %d", len(want.SomeFloatArray), len(got.SomeFloatArray))
+	} else {
+		for i := range want.SomeFloatArray {
+			if !floatEqual(want.SomeFloatArray[i], got.SomeFloatArray[i], tol) {
+				add("SomeFloatArray[%d]: want %v, got %v", i, want.SomeFloatArray[i], got.SomeFloatArray[i])
+			}
+		}
+	}
+
+	diffTestStructs("Tests", want.Tests, got.Tests, add)
+
+	switch {
+	case want.Comment == nil && got.Comment != nil:
+		add("Comment: want nil, got %q", *got.Comment)
+	case want.Comment != nil && got.Comment == nil:
+		add("Comment: want %q, got nil", *want.Comment)
+	case want.Comment != nil && *want.Comment != *got.Comment:
+		add("Comment: want %q, got %q", *want.Comment, *got.Comment)
+	}
+
+	if len(want.Labels) != len(got.Labels) {
+		add("Labels: want %d entries, got %d", len(want.Labels), len(got.Labels))
+	} else {
+		for k, v := range want.Labels {
+			if gv, ok := got.Labels[k]; !ok || gv != v {
+				add("Labels[%q]: want %q, got %q", k, v, gv)
+			}
+		}
+	}
+
+	if !bytes.Equal(want.Blob, got.Blob) {
+		add("Blob: want %d bytes %x, got %d bytes %x", len(want.Blob), want.Blob, len(got.Blob), got.Blob)
+	}
+
+	return diffs
+}
+
+func diffTestStructs(path string, want, got []TestStruct, add func(string, ...interface{})) {
+	if len(want) != len(got) {
+		add("%s: want %d elements, got %d", path, len(want), len(got))
+		return
+	}
+	for i := range want {
+		if want[i].Some != got[i].Some {
+			add("%s[%d].Some: want %q, got %q", path, i, want[i].Some, got[i].Some)
+		}
+		if want[i].Other != got[i].Other {
+			add("%s[%d].Other: want %q, got %q", path, i, want[i].Other, got[i].Other)
+		}
+		diffTestStructs(fmt.Sprintf("%s[%d].Children", path, i), want[i].Children, got[i].Children, add)
+	}
+}
+
+func floatEqual(a, b float32, tol float64) bool {
+	x, y := float64(a), float64(b)
+	if math.IsNaN(x) || math.IsNaN(y) {
+		return math.IsNaN(x) && math.IsNaN(y)
+	}
+	if x == y {
+		return true
+	}
+	if math.IsInf(x, 0) || math.IsInf(y, 0) {
+		return false
+	}
+	return math.Abs(x-y) <= tol*math.Max(math.Abs(x), math.Abs(y))
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func verifyTest() Test {
+	return Test{
+		ID:               1,
+		Name:             "name",
+		SomeNumericArray: []int32{1, 2, 3},
+		SomeFloatArray:   []float32{0.5, 1e-3, float32(math.NaN())},
+		Tests:            []TestStruct{{Some: "a", Other: "b", Children: []TestStruct{{Some: "c"}}}},
+		Comment:          strptr("comment"),
+		Labels:           Labels{"k": "v"},
+		Blob:             []byte{1, 2},
+	}
+}
+
+func TestDiffTest(t *testing.T) {
+	cases := []struct {
+		name string
+		edit func(t *Test)
+		tol  float64
+		want []string
+	}{
+		{"same", func(t *Test) {}, 0, nil},
+		{"nil and empty", func(t *Test) {
+			t.SomeNumericArray, t.Labels, t.Blob = nil, nil, nil
+		}, 0, []string{"SomeNumericArray: want 3 elements, got 0", "Labels: want 1 entries, got 0", "Blob: want 2 bytes 0102, got 0 bytes "}},
+		{"float within tolerance", func(t *Test) { t.SomeFloatArray[0] = 0.5000001 }, 1e-6, nil},
+		{"float beyond tolerance", func(t *Test) { t.SomeFloatArray[0] = 0.5001 }, 1e-6, []string{"SomeFloatArray[0]: want 0.5, got 0.5001"}},
+		{"scalars", func(t *Test) {
+			t.ID, t.Name = 2, "other"
+		}, 0, []string{"ID: want 1, got 2", `Name: want "name", got "other"`}},
+		{"nested", func(t *Test) {
+			t.Tests = []TestStruct{{Some: "a", Other: "b", Children: []TestStruct{{Some: "d"}}}}
+		}, 0, []string{`Tests[0].Children[0].Some: want "c", got "d"`}},
+		{"comment", func(t *Test) { t.Comment = nil }, 0, []string{`Comment: want "comment", got nil`}},
+		{"labels", func(t *Test) { t.Labels = Labels{"k": "w"} }, 0, []string{`Labels["k"]: want "v", got "w"`}},
+		{"too many", func(t *Test) {
+			t.SomeNumericArray = make([]int32, 3)
+			t.Tests = []TestStruct{{}}
+			t.Name, t.ID, t.Comment, t.Labels, t.Blob = "", 0, strptr(""), Labels{"k": ""}, nil
+			t.SomeFloatArray = []float32{0, 0, 0}
+		}, 0, []string{
+			"ID: want 1, got 0", `Name: want "name", got ""`,
+			"SomeNumericArray[0]: want 1, got 0", "SomeNumericArray[1]: want 2, got 0", "SomeNumericArray[2]: want 3, got 0",
+			"SomeFloatArray[0]: want 0.5, got 0", "SomeFloatArray[1]: want 0.001, got 0", "SomeFloatArray[2]: want NaN, got 0",
+			`Tests[0].Some: want "a", got ""`, `Tests[0].Other: want "b", got ""`, "...",
+		}},
+	}
+	for _, c := range cases {
+		got := verifyTest()
+		c.edit(&got)
+		if diffs := diffTest(verifyTest(), got, c.tol); !reflect.DeepEqual(diffs, c.want) {
+			t.Errorf("%s:\n got %q\nwant %q", c.name, diffs, c.want)
+		}
+	}
+}
+
+func TestDiffTestNilEmpty(t *testing.T) {
+	want := Test{SomeNumericArray: []int32{}, Tests: []TestStruct{}, Labels: Labels{}, Blob: []byte{}}
+	if diffs := diffTest(want, Test{}, 0); len(diffs) > 0 {
+		t.Errorf("nil and empty differ: %v", diffs)
+	}
+}
+
+func TestFloatEqual(t *testing.T) {
+	nan := float32(math.NaN())
+	cases := []struct {
+		a, b float32
+		tol  float64
+		want bool
+	}{
+		{1, 1, 0, true},
+		{1, 1.0000001, 0, false},
+		{1, 1.0000001, 1e-6, true},
+		{100, 100.01, 1e-6, false},
+		{100, 100.01, 1e-3, true},
+		{0, 1e-30, 1e-6, false},
+		{nan, nan, 0, true},
+		{nan, 1, 1, false},
+		{float32(math.Inf(1)), float32(math.Inf(1)), 0, true},
+		{float32(math.Inf(1)), float32(math.Inf(-1)), 1e-6, false},
+	}
+	for _, c := range cases {
+		if got := floatEqual(c.a, c.b, c.tol); got != c.want {
+			t.Errorf("floatEqual(%v, %v, %v) = %v, want %v", c.a, c.b, c.tol, got, c.want)
+		}
+	}
+}
+
+func TestCheckRun(t *testing.T) {
+	c := lookupCodec("Json")
+	want := verifyTest()
+	decoded := verifyTest()
+	if diffs := checkRun(c, want, runResult{decoded: &decoded}, 0); len(diffs) > 0 {
+		t.Errorf("same test: %v", diffs)
+	}
+	// With -runs 0 nothing is decoded, which must not pass as a clean run.
+	if diffs := checkRun(c, want, runResult{}, 0); len(diffs) == 0 {
+		t.Error("a run without a decoded value passed")
+	}
+}